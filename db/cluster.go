@@ -0,0 +1,139 @@
+package db
+
+import (
+	"api3/src/utils"
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Cluster reparte las consultas entre la BD principal y sus réplicas de
+// lectura. Las escrituras siempre van a la principal; las lecturas van a una
+// réplica sana salvo que quien lee haya escrito hace poco, para que quien
+// acaba de actualizar un usuario lo lea ya actualizado. La ventana es de
+// cada cliente (el usuario del token): las escrituras de uno no mandan a
+// todos los demás a la principal.
+type Cluster struct {
+	primary  *gorm.DB
+	replicas []replica
+	window   time.Duration
+
+	next atomic.Uint32
+
+	mu         sync.Mutex
+	lastWrites map[string]time.Time // última escritura de cada cliente
+}
+
+// Con más clientes que estos se olvidan las ventanas ya cerradas
+const pruneWritesAbove = 1024
+
+type replica struct {
+	db      *gorm.DB
+	monitor *Monitor
+}
+
+type primaryKey struct{}
+
+// Default es el cluster creado por ConnectDB
+var Default *Cluster
+
+// NewCluster crea un cluster; sin réplicas todas las consultas van a primary
+func NewCluster(primary *gorm.DB, readYourWritesWindow time.Duration) *Cluster {
+	return &Cluster{primary: primary, window: readYourWritesWindow}
+}
+
+// AddReplica registra una réplica de lectura vigilada por monitor
+func (c *Cluster) AddReplica(replicaDB *gorm.DB, monitor *Monitor) {
+	c.replicas = append(c.replicas, replica{db: replicaDB, monitor: monitor})
+}
+
+// Writer devuelve la conexión principal
+func (c *Cluster) Writer(ctx context.Context) *gorm.DB {
+	return c.primary.WithContext(ctx)
+}
+
+// Reader devuelve una réplica sana (round robin) o la principal si no hay
+// réplicas disponibles, si ctx lo pide o si el cliente de ctx escribió hace poco
+func (c *Cluster) Reader(ctx context.Context) *gorm.DB {
+	if len(c.replicas) == 0 || c.recentWrite(ctx) {
+		return c.Writer(ctx)
+	}
+	if force, _ := ctx.Value(primaryKey{}).(bool); force {
+		return c.Writer(ctx)
+	}
+
+	start := c.next.Add(1)
+	for i := range c.replicas {
+		r := c.replicas[(int(start)+i)%len(c.replicas)]
+		if r.monitor.Status().Status != StatusDown {
+			return r.db.WithContext(ctx)
+		}
+	}
+	return c.Writer(ctx)
+}
+
+// IsPrimary indica si tx apunta a la BD principal
+func (c *Cluster) IsPrimary(tx *gorm.DB) bool {
+	return tx.ConnPool == c.primary.ConnPool
+}
+
+// MarkWrite abre la ventana de "leer lo que escribiste" del cliente de ctx.
+// Sin token (p. ej. el alta pública) no hay a quién asociarla.
+func (c *Cluster) MarkWrite(ctx context.Context) {
+	client, ok := clientOf(ctx)
+	if !ok || c.window <= 0 {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lastWrites == nil {
+		c.lastWrites = map[string]time.Time{}
+	}
+	if len(c.lastWrites) >= pruneWritesAbove {
+		for k, at := range c.lastWrites {
+			if now.Sub(at) >= c.window {
+				delete(c.lastWrites, k)
+			}
+		}
+	}
+	c.lastWrites[client] = now
+}
+
+func (c *Cluster) recentWrite(ctx context.Context) bool {
+	client, ok := clientOf(ctx)
+	if !ok {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	at, ok := c.lastWrites[client]
+	return ok && time.Since(at) < c.window
+}
+
+// El cliente es el usuario del token de la petición
+func clientOf(ctx context.Context) (string, bool) {
+	claims, ok := utils.ClaimsFrom(ctx)
+	if !ok {
+		return "", false
+	}
+	return strconv.FormatUint(uint64(claims.UserID), 10), true
+}
+
+// ReplicaHealth devuelve el último estado conocido de cada réplica
+func (c *Cluster) ReplicaHealth() []HealthStatus {
+	status := make([]HealthStatus, 0, len(c.replicas))
+	for _, r := range c.replicas {
+		status = append(status, r.monitor.Status())
+	}
+	return status
+}
+
+// WithPrimary marca ctx para que las lecturas vayan a la BD principal
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"api3/src/utils"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReadYourWritesPerClient(t *testing.T) {
+	primary, replica := openMemory(t, "primary"), openMemory(t, "replica")
+	replicaSQL, _ := replica.DB()
+	c := NewCluster(primary, time.Minute)
+	c.AddReplica(replica, NewMonitor("replica", replicaSQL))
+
+	writer := utils.WithClaims(context.Background(), &utils.Claims{UserID: 1})
+	other := utils.WithClaims(context.Background(), &utils.Claims{UserID: 2})
+	anonymous := context.Background()
+
+	c.MarkWrite(writer)
+	c.MarkWrite(anonymous) // sin cliente no abre ninguna ventana
+	for _, tt := range []struct {
+		name    string
+		ctx     context.Context
+		primary bool
+	}{
+		{"quien escribió", writer, true},
+		{"otro usuario", other, false},
+		{"sin token", anonymous, false},
+		{"forzado", WithPrimary(other), true},
+	} {
+		if got := c.IsPrimary(c.Reader(tt.ctx)); got != tt.primary {
+			t.Errorf("%s: lee de la principal = %v, se esperaba %v", tt.name, got, tt.primary)
+		}
+	}
+
+	// Cerrada la ventana vuelve a leer de la réplica
+	c.window = 0
+	if c.IsPrimary(c.Reader(writer)) {
+		t.Error("tras la ventana sigue leyendo de la principal")
+	}
+}
@@ -1,30 +1,231 @@
 package db
 
 import (
-	"api3/src/models"
+	"api3/src/utils"
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+// Drivers soportados (coinciden con Dialector.Name() de GORM)
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 var DB *gorm.DB
 
+// Config indica a qué base de datos conectarse y cómo gestionar el pool
+type Config struct {
+	Driver string
+	DSN    string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// Reintentos al arrancar: la espera empieza en RetryBackoff y se duplica
+	// hasta RetryMaxBackoff
+	ConnectRetries  int
+	RetryBackoff    time.Duration
+	RetryMaxBackoff time.Duration
+
+	PingInterval time.Duration
+
+	// Réplicas de lectura (mismo driver que la principal)
+	ReplicaDSNs          []string
+	ReadYourWritesWindow time.Duration
+}
+
+// LoadConfig lee DB_DRIVER, DB_DSN y los ajustes del pool. Por compatibilidad,
+// si DB_DSN no está definido con MySQL se usa MYSQLCONN, y con SQLite el
+// archivo api3.db.
+func LoadConfig() Config {
+	cfg := Config{
+		Driver:          strings.ToLower(os.Getenv("DB_DRIVER")),
+		DSN:             os.Getenv("DB_DSN"),
+		MaxOpenConns:    utils.EnvInt("DB_MAX_OPEN_CONNS", 25),
+		MaxIdleConns:    utils.EnvInt("DB_MAX_IDLE_CONNS", 5),
+		ConnMaxLifetime: utils.EnvDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+		ConnMaxIdleTime: utils.EnvDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+		ConnectRetries:  utils.EnvInt("DB_CONNECT_RETRIES", 10),
+		RetryBackoff:    utils.EnvDuration("DB_RETRY_BACKOFF", time.Second),
+		RetryMaxBackoff: utils.EnvDuration("DB_RETRY_MAX_BACKOFF", 30*time.Second),
+		PingInterval:    utils.EnvDuration("DB_PING_INTERVAL", 15*time.Second),
+
+		ReadYourWritesWindow: utils.EnvDuration("DB_READ_YOUR_WRITES_WINDOW", 5*time.Second),
+	}
+	for _, dsn := range strings.Split(os.Getenv("DB_REPLICA_DSNS"), ",") {
+		if dsn = strings.TrimSpace(dsn); dsn != "" {
+			cfg.ReplicaDSNs = append(cfg.ReplicaDSNs, dsn)
+		}
+	}
+	if cfg.Driver == "" {
+		cfg.Driver = DriverMySQL
+	}
+	if cfg.DSN == "" {
+		switch cfg.Driver {
+		case DriverMySQL:
+			cfg.DSN = os.Getenv("MYSQLCONN")
+		case DriverSQLite:
+			cfg.DSN = "api3.db"
+		}
+	}
+	return cfg
+}
+
+// Open abre una conexión GORM con el driver de la configuración
+func Open(cfg Config) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch cfg.Driver {
+	case DriverMySQL:
+		dialector = mysql.Open(cfg.DSN)
+	case DriverPostgres:
+		dialector = postgres.Open(cfg.DSN)
+	case DriverSQLite:
+		dialector = sqlite.Open(cfg.DSN)
+	default:
+		return nil, fmt.Errorf("driver de BD no soportado: %q (usa mysql, postgres o sqlite)", cfg.Driver)
+	}
+
+	// TranslateError convierte las violaciones de índices únicos de los tres
+	// drivers en gorm.ErrDuplicatedKey
+	gormDB, err := gorm.Open(dialector, &gorm.Config{TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sqlDB.PingContext(ctx); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+	return gormDB, nil
+}
+
+// OpenWithRetry reintenta Open con backoff exponencial; en docker-compose la
+// BD suele tardar más que la API en aceptar conexiones
+func OpenWithRetry(cfg Config) (*gorm.DB, error) {
+	backoff := cfg.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		gormDB, err := Open(cfg)
+		if err == nil {
+			return gormDB, nil
+		}
+		if attempt > cfg.ConnectRetries {
+			return nil, fmt.Errorf("sin conexión tras %d intentos: %w", attempt, err)
+		}
+
+		log.Printf("⏳ BD no disponible (intento %d/%d): %v; reintentando en %s", attempt, cfg.ConnectRetries+1, err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > cfg.RetryMaxBackoff {
+			backoff = cfg.RetryMaxBackoff
+		}
+	}
+}
+
 func ConnectDB() {
-	dsn := os.Getenv("MYSQLCONN")
+	cfg := LoadConfig()
 
 	var err error
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	DB, err = OpenWithRetry(cfg)
 	if err != nil {
 		log.Fatal("❌ Error al conectar con la BD:", err)
 	}
 
-	err = DB.AutoMigrate(&models.User{})
+	sqlDB, _ := DB.DB()
+	primaryMonitor = NewMonitor("primary", sqlDB)
+	primaryMonitor.Start(context.Background(), cfg.PingInterval)
+
+	Default = NewCluster(DB, cfg.ReadYourWritesWindow)
+	for i, dsn := range cfg.ReplicaDSNs {
+		name := fmt.Sprintf("replica-%d", i+1)
+		replicaCfg := cfg
+		replicaCfg.DSN = dsn
+
+		// Una réplica caída no debe impedir el arranque: las lecturas irán a la principal
+		replicaDB, err := Open(replicaCfg)
+		if err != nil {
+			log.Printf("⚠️  Réplica %s no disponible, se ignora: %v", name, err)
+			continue
+		}
+		replicaSQL, _ := replicaDB.DB()
+		monitor := NewMonitor(name, replicaSQL)
+		monitor.Start(context.Background(), cfg.PingInterval)
+		Default.AddReplica(replicaDB, monitor)
+	}
+
+	fmt.Printf("✅ Conectado a la BD (%s, %d réplicas)\n", cfg.Driver, len(Default.replicas))
+}
+
+// NewDefaultMigrator crea un Migrator sobre la conexión global
+func NewDefaultMigrator() (*Migrator, error) {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return nil, err
+	}
+	return NewMigrator(sqlDB, DB.Dialector.Name())
+}
+
+// AutoMigrateEnabled indica si DB_AUTO_MIGRATE permite aplicar migraciones al arrancar
+func AutoMigrateEnabled() bool {
+	switch strings.ToLower(os.Getenv("DB_AUTO_MIGRATE")) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
+
+// CheckSchema verifica que no haya migraciones pendientes. Si autoApply es
+// true las aplica; si no, devuelve un error para que la API no arranque con
+// un esquema desactualizado.
+func CheckSchema(ctx context.Context, autoApply bool) error {
+	migrator, err := NewDefaultMigrator()
 	if err != nil {
-		log.Fatal("❌ Error al migrar modelo User:", err)
+		return err
 	}
 
-	fmt.Println("✅ Conectado a MySQL y tabla 'users' lista")
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		fmt.Println("✅ Esquema de la BD al día")
+		return nil
+	}
+
+	if !autoApply {
+		return fmt.Errorf("hay %d migraciones pendientes (la primera es %d_%s); ejecuta `migrate up` o define DB_AUTO_MIGRATE=true",
+			len(pending), pending[0].Version, pending[0].Name)
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return err
+	}
+	for _, m := range applied {
+		fmt.Printf("✅ Migración aplicada: %d_%s\n", m.Version, m.Name)
+	}
+	return nil
 }
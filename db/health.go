@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// Estados posibles de una conexión
+const (
+	StatusUp      = "up"
+	StatusDown    = "down"
+	StatusUnknown = "unknown"
+)
+
+// HealthStatus es el resultado del último ping a una base de datos
+type HealthStatus struct {
+	Name            string    `json:"name"`
+	Status          string    `json:"status"`
+	Error           string    `json:"error,omitempty"`
+	LatencyMs       int64     `json:"latencyMs"`
+	CheckedAt       time.Time `json:"checkedAt"`
+	OpenConnections int       `json:"openConnections"`
+	InUse           int       `json:"inUse"`
+	Idle            int       `json:"idle"`
+}
+
+// Monitor hace ping periódico a una base de datos y guarda su estado
+type Monitor struct {
+	name string
+	db   *sql.DB
+
+	mu     sync.RWMutex
+	status HealthStatus
+}
+
+var primaryMonitor *Monitor
+
+func NewMonitor(name string, sqlDB *sql.DB) *Monitor {
+	return &Monitor{
+		name:   name,
+		db:     sqlDB,
+		status: HealthStatus{Name: name, Status: StatusUnknown},
+	}
+}
+
+// Check hace un ping inmediato y actualiza el estado
+func (m *Monitor) Check(ctx context.Context) HealthStatus {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err := m.db.PingContext(ctx)
+	stats := m.db.Stats()
+
+	status := HealthStatus{
+		Name:            m.name,
+		Status:          StatusUp,
+		LatencyMs:       time.Since(start).Milliseconds(),
+		CheckedAt:       time.Now().UTC(),
+		OpenConnections: stats.OpenConnections,
+		InUse:           stats.InUse,
+		Idle:            stats.Idle,
+	}
+	if err != nil {
+		status.Status = StatusDown
+		status.Error = err.Error()
+	}
+
+	m.mu.Lock()
+	m.status = status
+	m.mu.Unlock()
+	return status
+}
+
+// Start lanza el ping periódico hasta que se cancele ctx
+func (m *Monitor) Start(ctx context.Context, interval time.Duration) {
+	m.Check(ctx)
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.Check(ctx)
+			}
+		}
+	}()
+}
+
+// Status devuelve el último estado conocido sin hacer ping
+func (m *Monitor) Status() HealthStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// Health devuelve el estado de la BD principal
+func Health() HealthStatus {
+	if primaryMonitor == nil {
+		return HealthStatus{Name: "primary", Status: StatusUnknown}
+	}
+	return primaryMonitor.Status()
+}
+
+// ReplicaHealth devuelve el estado de las réplicas de lectura configuradas
+func ReplicaHealth() []HealthStatus {
+	if Default == nil {
+		return nil
+	}
+	return Default.ReplicaHealth()
+}
@@ -0,0 +1,167 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// openMemory abre la BD SQLite en memoria name. Se mantiene una conexión
+// abierta: la BD desaparece al cerrarse la última.
+func openMemory(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	gormDB, err := Open(Config{Driver: DriverSQLite, DSN: fmt.Sprintf("file:%s?mode=memory&cache=shared", name), MaxIdleConns: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := gormDB.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	return gormDB
+}
+
+func newTestMigrator(t *testing.T) *Migrator {
+	t.Helper()
+	sqlDB, _ := openMemory(t, t.Name()).DB()
+	migrator, err := NewMigrator(sqlDB, DriverSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return migrator
+}
+
+// Versión más alta aplicada (0 si ninguna)
+func schemaVersion(t *testing.T, m *Migrator) int {
+	t.Helper()
+	status, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := 0
+	for _, s := range status {
+		if s.Applied {
+			version = s.Version
+		}
+	}
+	return version
+}
+
+func tables(t *testing.T, m *Migrator) []string {
+	t.Helper()
+	rows, err := m.db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestMigrateUpDownUp(t *testing.T) {
+	ctx := context.Background()
+	m := newTestMigrator(t)
+	latest := m.migrations[len(m.migrations)-1].Version
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(m.migrations) || schemaVersion(t, m) != latest {
+		t.Fatalf("up aplicó %d migraciones, versión %d; se esperaba %d", len(applied), schemaVersion(t, m), latest)
+	}
+	withSchema := tables(t, m)
+
+	reverted, err := m.Down(ctx, len(m.migrations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reverted) != len(m.migrations) || schemaVersion(t, m) != 0 {
+		t.Fatalf("down revirtió %d migraciones, versión %d", len(reverted), schemaVersion(t, m))
+	}
+	if left := tables(t, m); len(left) != 1 || left[0] != "schema_migrations" {
+		t.Errorf("tras revertir quedan las tablas %v", left)
+	}
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("segundo up: %v", err)
+	}
+	if schemaVersion(t, m) != latest || fmt.Sprint(tables(t, m)) != fmt.Sprint(withSchema) {
+		t.Errorf("el segundo up deja la versión %d y las tablas %v; se esperaba %d y %v", schemaVersion(t, m), tables(t, m), latest, withSchema)
+	}
+	if pending, _ := m.Pending(ctx); len(pending) != 0 {
+		t.Errorf("quedan %d migraciones pendientes", len(pending))
+	}
+}
+
+// Los tres dialectos deben tener las mismas migraciones, todas reversibles
+func TestDialectsInSync(t *testing.T) {
+	names := map[string][]string{}
+	for _, dialect := range []string{DriverMySQL, DriverPostgres, DriverSQLite} {
+		migrations, err := LoadMigrations(dialect)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range migrations {
+			if m.Down == "" {
+				t.Errorf("%s: la migración %d_%s no tiene script down", dialect, m.Version, m.Name)
+			}
+			names[dialect] = append(names[dialect], fmt.Sprintf("%d_%s", m.Version, m.Name))
+		}
+		sort.Strings(names[dialect])
+	}
+	want := fmt.Sprint(names[DriverSQLite])
+	for _, dialect := range []string{DriverMySQL, DriverPostgres} {
+		if got := fmt.Sprint(names[dialect]); got != want {
+			t.Errorf("%s tiene %s; sqlite tiene %s", dialect, got, want)
+		}
+	}
+}
+
+// Las filas antiguas con role o zona NULL quedan rellenadas y el contador de
+// IDs no retrocede al reconstruir la tabla en SQLite
+func TestUsersRoleZonaNotNull(t *testing.T) {
+	ctx := context.Background()
+	m := newTestMigrator(t)
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Down(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"INSERT INTO users (username, password, role, zona) VALUES ('ana', 'x', NULL, NULL), ('bea', 'x', 'admin', 'sur'), ('carla', 'x', 'user', 'norte')",
+		"DELETE FROM users WHERE username = 'carla'",
+	} {
+		if _, err := m.db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var role, zona string
+	if err := m.db.QueryRow("SELECT role, zona FROM users WHERE username = 'ana'").Scan(&role, &zona); err != nil {
+		t.Fatal(err)
+	}
+	if role != "user" || zona != "" {
+		t.Errorf("ana quedó con role %q y zona %q", role, zona)
+	}
+	if _, err := m.db.Exec("INSERT INTO users (username, password, zona) VALUES ('dora', 'x', NULL)"); err == nil {
+		t.Error("se admitió una zona NULL")
+	}
+	var id int
+	if err := m.db.QueryRow("INSERT INTO users (username, password) VALUES ('eva', 'x') RETURNING id").Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	if id != 4 {
+		t.Errorf("el nuevo usuario recibió el ID %d, se esperaba 4", id)
+	}
+}
@@ -0,0 +1,13 @@
+-- Tabla creada originalmente por AutoMigrate; IF NOT EXISTS permite
+-- adoptar bases de datos existentes sin perder datos.
+CREATE TABLE IF NOT EXISTS users (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    username LONGTEXT,
+    password LONGTEXT,
+    role LONGTEXT,
+    zona LONGTEXT,
+    image LONGBLOB,
+    image_str LONGTEXT,
+    mime_type LONGTEXT,
+    PRIMARY KEY (id)
+);
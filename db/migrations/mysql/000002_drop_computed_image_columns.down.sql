@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN image_str LONGTEXT, ADD COLUMN mime_type LONGTEXT;
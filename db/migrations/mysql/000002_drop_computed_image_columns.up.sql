@@ -0,0 +1,2 @@
+-- image_str y mime_type se calculan al leer la imagen, nunca se guardaron datos útiles.
+ALTER TABLE users DROP COLUMN image_str, DROP COLUMN mime_type;
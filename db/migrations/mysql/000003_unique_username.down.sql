@@ -0,0 +1,2 @@
+DROP INDEX idx_users_username ON users;
+ALTER TABLE users MODIFY username LONGTEXT;
@@ -0,0 +1,2 @@
+ALTER TABLE users MODIFY username VARCHAR(191) NOT NULL;
+CREATE UNIQUE INDEX idx_users_username ON users (username);
@@ -0,0 +1,13 @@
+-- Imágenes direccionadas por contenido: la clave es el SHA-256 del original
+-- y ref_count cuenta los usuarios que la usan. ref_count = -1 marca una
+-- imagen que el recolector está borrando.
+CREATE TABLE images (
+    hash CHAR(64) NOT NULL,
+    ref_count INT NOT NULL DEFAULT 0,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    PRIMARY KEY (hash),
+    INDEX idx_images_gc (ref_count, updated_at)
+);
@@ -0,0 +1,7 @@
+DROP INDEX idx_users_status ON users;
+DROP INDEX idx_users_zona ON users;
+DROP INDEX idx_users_role ON users;
+ALTER TABLE users
+    DROP COLUMN status,
+    MODIFY role LONGTEXT,
+    MODIFY zona LONGTEXT;
@@ -0,0 +1,8 @@
+-- Estado del usuario y columnas indexables para filtrar el listado
+ALTER TABLE users
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active',
+    MODIFY role VARCHAR(50),
+    MODIFY zona VARCHAR(100);
+CREATE INDEX idx_users_role ON users (role);
+CREATE INDEX idx_users_zona ON users (zona);
+CREATE INDEX idx_users_status ON users (status);
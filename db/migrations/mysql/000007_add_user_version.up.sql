@@ -0,0 +1,5 @@
+-- Versión para el control de concurrencia optimista (ETag / If-Match)
+ALTER TABLE users
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 1,
+    ADD COLUMN updated_at DATETIME(3) NULL;
+UPDATE users SET updated_at = CURRENT_TIMESTAMP(3);
@@ -0,0 +1,8 @@
+-- Zonas válidas para los usuarios; se cargan las que ya estaban en uso
+CREATE TABLE zones (
+    name VARCHAR(100) NOT NULL,
+    created_at DATETIME(3) NULL,
+    PRIMARY KEY (name)
+);
+INSERT INTO zones (name, created_at)
+    SELECT DISTINCT zona, CURRENT_TIMESTAMP(3) FROM users WHERE zona IS NOT NULL AND zona <> '';
@@ -0,0 +1,3 @@
+-- Nombre para mostrar e índice de texto completo para /users/search
+ALTER TABLE users ADD COLUMN display_name VARCHAR(100) NOT NULL DEFAULT '';
+CREATE FULLTEXT INDEX idx_users_search ON users (username, display_name, zona);
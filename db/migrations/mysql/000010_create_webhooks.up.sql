@@ -0,0 +1,46 @@
+-- Suscripciones a los cambios de usuarios. events es la lista de tipos
+-- separados por comas (vacía: todos); secret firma las entregas.
+CREATE TABLE webhooks (
+    id INT NOT NULL AUTO_INCREMENT,
+    url VARCHAR(2000) NOT NULL,
+    secret VARCHAR(200) NOT NULL,
+    events VARCHAR(200) NOT NULL DEFAULT '',
+    description VARCHAR(200) NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at DATETIME(3) NULL,
+    updated_at DATETIME(3) NULL,
+    PRIMARY KEY (id)
+);
+-- Una entrega por evento y webhook: pending hasta que el receptor responde
+-- 2xx (delivered) o se agotan los intentos (dead)
+CREATE TABLE webhook_deliveries (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    webhook_id INT NOT NULL,
+    event_id BIGINT UNSIGNED NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payload MEDIUMTEXT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME(3) NULL,
+    last_status INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL,
+    created_at DATETIME(3) NULL,
+    updated_at DATETIME(3) NULL,
+    PRIMARY KEY (id),
+    INDEX idx_webhook_deliveries_due (status, next_attempt_at),
+    INDEX idx_webhook_deliveries_webhook (webhook_id, id),
+    FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE
+);
+-- Registro de cada intento de entrega
+CREATE TABLE webhook_attempts (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    delivery_id BIGINT NOT NULL,
+    attempt INT NOT NULL,
+    status_code INT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    created_at DATETIME(3) NULL,
+    PRIMARY KEY (id),
+    INDEX idx_webhook_attempts_delivery (delivery_id),
+    FOREIGN KEY (delivery_id) REFERENCES webhook_deliveries (id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    MODIFY role VARCHAR(50),
+    MODIFY zona VARCHAR(100);
@@ -0,0 +1,7 @@
+-- role y zona ordenan el listado por cursor; un NULL nunca cumple la
+-- comparación del cursor y la fila desaparecía de la paginación
+UPDATE users SET role = 'user' WHERE role IS NULL;
+UPDATE users SET zona = '' WHERE zona IS NULL;
+ALTER TABLE users
+    MODIFY role VARCHAR(50) NOT NULL DEFAULT 'user',
+    MODIFY zona VARCHAR(100) NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL PRIMARY KEY,
+    username TEXT,
+    password TEXT,
+    role TEXT,
+    zona TEXT,
+    image BYTEA,
+    image_str TEXT,
+    mime_type TEXT
+);
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN image_str TEXT, ADD COLUMN mime_type TEXT;
@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN image_str, DROP COLUMN mime_type;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_users_username;
+ALTER TABLE users ALTER COLUMN username DROP NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE users ALTER COLUMN username SET NOT NULL;
+CREATE UNIQUE INDEX idx_users_username ON users (username);
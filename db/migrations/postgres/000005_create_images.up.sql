@@ -0,0 +1,12 @@
+-- Imágenes direccionadas por contenido: la clave es el SHA-256 del original
+-- y ref_count cuenta los usuarios que la usan. ref_count = -1 marca una
+-- imagen que el recolector está borrando.
+CREATE TABLE images (
+    hash CHAR(64) PRIMARY KEY,
+    ref_count INTEGER NOT NULL DEFAULT 0,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+CREATE INDEX idx_images_gc ON images (ref_count, updated_at);
@@ -0,0 +1,4 @@
+-- Versión para el control de concurrencia optimista (ETag / If-Match)
+ALTER TABLE users ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN updated_at TIMESTAMPTZ;
+UPDATE users SET updated_at = CURRENT_TIMESTAMP;
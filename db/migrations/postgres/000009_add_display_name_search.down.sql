@@ -0,0 +1,2 @@
+DROP INDEX idx_users_search;
+ALTER TABLE users DROP COLUMN display_name;
@@ -0,0 +1,6 @@
+-- Nombre para mostrar e índice de texto completo para /users/search. La
+-- expresión debe coincidir con la de la consulta para que se use el índice.
+ALTER TABLE users ADD COLUMN display_name VARCHAR(100) NOT NULL DEFAULT '';
+CREATE INDEX idx_users_search ON users USING GIN (
+    to_tsvector('simple', coalesce(username, '') || ' ' || display_name || ' ' || coalesce(zona, ''))
+);
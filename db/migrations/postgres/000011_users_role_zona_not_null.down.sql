@@ -0,0 +1,4 @@
+ALTER TABLE users ALTER COLUMN zona DROP NOT NULL;
+ALTER TABLE users ALTER COLUMN zona DROP DEFAULT;
+ALTER TABLE users ALTER COLUMN role DROP NOT NULL;
+ALTER TABLE users ALTER COLUMN role DROP DEFAULT;
@@ -0,0 +1,8 @@
+-- role y zona ordenan el listado por cursor; un NULL nunca cumple la
+-- comparación del cursor y la fila desaparecía de la paginación
+UPDATE users SET role = 'user' WHERE role IS NULL;
+UPDATE users SET zona = '' WHERE zona IS NULL;
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'user';
+ALTER TABLE users ALTER COLUMN role SET NOT NULL;
+ALTER TABLE users ALTER COLUMN zona SET DEFAULT '';
+ALTER TABLE users ALTER COLUMN zona SET NOT NULL;
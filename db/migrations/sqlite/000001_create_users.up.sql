@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username TEXT,
+    password TEXT,
+    role TEXT,
+    zona TEXT,
+    image BLOB,
+    image_str TEXT,
+    mime_type TEXT
+);
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN image_str TEXT;
+ALTER TABLE users ADD COLUMN mime_type TEXT;
@@ -0,0 +1,3 @@
+-- SQLite solo elimina una columna por sentencia
+ALTER TABLE users DROP COLUMN image_str;
+ALTER TABLE users DROP COLUMN mime_type;
@@ -0,0 +1,2 @@
+-- SQLite no permite añadir NOT NULL sin reconstruir la tabla; basta con el índice único
+CREATE UNIQUE INDEX idx_users_username ON users (username);
@@ -0,0 +1,3 @@
+-- Las imágenes pasan a un BlobStore; la columna image se conserva hasta
+-- ejecutar `api-zoo images migrate`, que mueve los datos y la deja en NULL.
+ALTER TABLE users ADD COLUMN image_key TEXT;
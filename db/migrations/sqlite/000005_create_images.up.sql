@@ -0,0 +1,12 @@
+-- Imágenes direccionadas por contenido: la clave es el SHA-256 del original
+-- y ref_count cuenta los usuarios que la usan. ref_count = -1 marca una
+-- imagen que el recolector está borrando.
+CREATE TABLE images (
+    hash TEXT PRIMARY KEY,
+    ref_count INTEGER NOT NULL DEFAULT 0,
+    content_type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+CREATE INDEX idx_images_gc ON images (ref_count, updated_at);
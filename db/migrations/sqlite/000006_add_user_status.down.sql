@@ -0,0 +1,4 @@
+DROP INDEX idx_users_status;
+DROP INDEX idx_users_zona;
+DROP INDEX idx_users_role;
+ALTER TABLE users DROP COLUMN status;
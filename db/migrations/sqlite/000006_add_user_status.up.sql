@@ -0,0 +1,5 @@
+-- Estado del usuario e índices para filtrar el listado
+ALTER TABLE users ADD COLUMN status TEXT NOT NULL DEFAULT 'active';
+CREATE INDEX idx_users_role ON users (role);
+CREATE INDEX idx_users_zona ON users (zona);
+CREATE INDEX idx_users_status ON users (status);
@@ -0,0 +1,4 @@
+-- Versión para el control de concurrencia optimista (ETag / If-Match)
+ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN updated_at DATETIME;
+UPDATE users SET updated_at = CURRENT_TIMESTAMP;
@@ -0,0 +1,7 @@
+-- Zonas válidas para los usuarios; se cargan las que ya estaban en uso
+CREATE TABLE zones (
+    name TEXT PRIMARY KEY,
+    created_at DATETIME
+);
+INSERT INTO zones (name, created_at)
+    SELECT DISTINCT zona, CURRENT_TIMESTAMP FROM users WHERE zona IS NOT NULL AND zona <> '';
@@ -0,0 +1,3 @@
+-- Nombre para mostrar. SQLite no tiene índice de texto completo aquí: la
+-- búsqueda usa un índice en memoria del proceso.
+ALTER TABLE users ADD COLUMN display_name TEXT NOT NULL DEFAULT '';
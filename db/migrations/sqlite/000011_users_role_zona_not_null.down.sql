@@ -0,0 +1,23 @@
+CREATE TABLE users_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username TEXT,
+    password TEXT,
+    role TEXT,
+    zona TEXT,
+    image BLOB,
+    image_key TEXT,
+    status TEXT NOT NULL DEFAULT 'active',
+    version INTEGER NOT NULL DEFAULT 1,
+    updated_at DATETIME,
+    display_name TEXT NOT NULL DEFAULT ''
+);
+INSERT INTO users_old (id, username, password, role, zona, image, image_key, status, version, updated_at, display_name)
+    SELECT id, username, password, role, zona, image, image_key, status, version, updated_at, display_name FROM users;
+DELETE FROM sqlite_sequence WHERE name = 'users_old';
+UPDATE sqlite_sequence SET name = 'users_old' WHERE name = 'users';
+DROP TABLE users;
+ALTER TABLE users_old RENAME TO users;
+CREATE UNIQUE INDEX idx_users_username ON users (username);
+CREATE INDEX idx_users_role ON users (role);
+CREATE INDEX idx_users_zona ON users (zona);
+CREATE INDEX idx_users_status ON users (status);
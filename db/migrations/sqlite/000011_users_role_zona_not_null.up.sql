@@ -0,0 +1,27 @@
+-- role y zona ordenan el listado por cursor; un NULL nunca cumple la
+-- comparación del cursor y la fila desaparecía de la paginación.
+-- SQLite no permite añadir NOT NULL sin reconstruir la tabla.
+CREATE TABLE users_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username TEXT,
+    password TEXT,
+    role TEXT NOT NULL DEFAULT 'user',
+    zona TEXT NOT NULL DEFAULT '',
+    image BLOB,
+    image_key TEXT,
+    status TEXT NOT NULL DEFAULT 'active',
+    version INTEGER NOT NULL DEFAULT 1,
+    updated_at DATETIME,
+    display_name TEXT NOT NULL DEFAULT ''
+);
+INSERT INTO users_new (id, username, password, role, zona, image, image_key, status, version, updated_at, display_name)
+    SELECT id, username, password, COALESCE(role, 'user'), COALESCE(zona, ''), image, image_key, status, version, updated_at, display_name FROM users;
+-- Conserva el contador de IDs para no reutilizar los de usuarios borrados
+DELETE FROM sqlite_sequence WHERE name = 'users_new';
+UPDATE sqlite_sequence SET name = 'users_new' WHERE name = 'users';
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+CREATE UNIQUE INDEX idx_users_username ON users (username);
+CREATE INDEX idx_users_role ON users (role);
+CREATE INDEX idx_users_zona ON users (zona);
+CREATE INDEX idx_users_status ON users (status);
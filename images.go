@@ -0,0 +1,57 @@
+package main
+
+import (
+	"api3/db"
+	"api3/src/images"
+	"api3/src/repository"
+	"api3/src/storage"
+	"api3/src/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const imagesUsage = "uso: api-zoo images migrate | gc"
+
+// Subcomando `images`: mueve las imágenes guardadas en la tabla users al
+// BlobStore o fuerza una pasada del recolector
+func runImages(args []string) {
+	if len(args) == 0 || (args[0] != "migrate" && args[0] != "gc") {
+		log.Fatal(imagesUsage)
+	}
+
+	db.ConnectDB()
+	blobs, err := storage.NewFromEnv()
+	if err != nil {
+		log.Fatal("❌ Error al preparar el almacén de imágenes:", err)
+	}
+
+	imageStore := images.NewStore(blobs, repository.NewGormImageRepository(db.Default))
+
+	if args[0] == "gc" {
+		removed, err := imageStore.CollectGarbage(context.Background(), utils.EnvDuration("IMAGE_GC_GRACE", time.Hour))
+		fmt.Printf("✅ Imágenes huérfanas eliminadas: %d\n", removed)
+		if err != nil {
+			log.Fatal("❌ Error en el recolector:", err)
+		}
+		return
+	}
+
+	// Las imágenes antiguas que no pasan la validación se guardan tal cual
+	save := func(ctx context.Context, image []byte) (string, error) {
+		key, err := imageStore.Save(ctx, image)
+		if errors.Is(err, images.ErrUnsupportedFormat) || errors.Is(err, images.ErrInvalidImage) ||
+			errors.Is(err, images.ErrTooLarge) || errors.Is(err, images.ErrTooManyPixels) {
+			return imageStore.SaveRaw(ctx, image)
+		}
+		return key, err
+	}
+
+	moved, err := repository.MoveImagesToBlobStore(context.Background(), db.DB, save, 100)
+	fmt.Printf("✅ Imágenes movidas al almacén: %d\n", moved)
+	if err != nil {
+		log.Fatal("❌ Error al mover imágenes:", err)
+	}
+}
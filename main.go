@@ -2,28 +2,106 @@ package main
 
 import (
 	"api3/db"
-	_ "api3/docs"
+	"api3/src/controllers"
+	"api3/src/images"
+	"api3/src/repository"
 	"api3/src/routes"
+	"api3/src/rpc"
+	"api3/src/storage"
 	"api3/src/utils"
-	"log"
+	"api3/src/webhooks"
+	"context"
 	"github.com/joho/godotenv"
+	"log"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
 func main() {
 	err := godotenv.Load(".env")
-    if err != nil {
-        log.Println("Advertencia: no se pudo cargar el archivo .env:", err)
-    }
+	if err != nil {
+		log.Println("Advertencia: no se pudo cargar el archivo .env:", err)
+	}
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			runMigrate(os.Args[2:])
+			return
+		case "images":
+			runImages(os.Args[2:])
+			return
+		case "openapi":
+			runOpenAPI()
+			return
+		}
+	}
+
 	db.ConnectDB()
-	r := routes.SetupRoutes()
-	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
+	if err := db.CheckSchema(context.Background(), db.AutoMigrateEnabled()); err != nil {
+		log.Fatal("❌ Esquema de la BD desactualizado: ", err)
+	}
+	blobs, err := storage.NewFromEnv()
+	if err != nil {
+		log.Fatal("❌ Error al preparar el almacén de imágenes: ", err)
+	}
+	imageStore := images.NewStore(blobs, repository.NewGormImageRepository(db.Default))
+	imageStore.StartGC(context.Background(), utils.EnvDuration("IMAGE_GC_INTERVAL", time.Hour), utils.EnvDuration("IMAGE_GC_GRACE", time.Hour))
+
+	zoneRepo := repository.NewGormZoneRepository(db.Default)
+	// ZONES=norte,sur da de alta las zonas iniciales en una BD nueva
+	if err := repository.EnsureZones(context.Background(), zoneRepo, splitList(os.Getenv("ZONES"))); err != nil {
+		log.Fatal("❌ Error al preparar las zonas: ", err)
+	}
+
+	// Webhooks: las entregas se reintentan con espera exponencial y, agotados
+	// los intentos, quedan en la lista de fallidas
+	webhookRepo := repository.NewGormWebhookRepository(db.Default)
+	dispatcher := webhooks.NewDispatcher(webhookRepo, webhooks.Config{
+		MaxAttempts: utils.EnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		Backoff:     utils.EnvDuration("WEBHOOK_BACKOFF", 30*time.Second),
+		MaxBackoff:  utils.EnvDuration("WEBHOOK_BACKOFF_MAX", time.Hour),
+		Timeout:     utils.EnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+	})
+	dispatcher.Start(context.Background(), utils.EnvDuration("WEBHOOK_POLL_INTERVAL", 5*time.Second))
+
+	users := controllers.NewUserHandler(repository.NewGormUserRepository(db.Default), zoneRepo, imageStore, dispatcher)
+	zones := controllers.NewZoneHandler(zoneRepo)
+	hooks := controllers.NewWebhookHandler(webhookRepo, dispatcher)
+	graph, err := controllers.NewGraphQLHandler(users, zones)
+	if err != nil {
+		log.Fatal("❌ Error en el esquema GraphQL: ", err)
+	}
+	health := controllers.NewHealthHandler(db.Health, db.ReplicaHealth)
+	r := routes.SetupRoutes(users, zones, hooks, graph, health)
+	// La interfaz de Swagger lee la especificación que generan las rutas
+	r.PathPrefix("/swagger/").Handler(httpSwagger.Handler(httpSwagger.URL("/openapi.json")))
 
 	handlerWithCORS := utils.CORS(r)
 
+	// gRPC para los demás servicios, en su propio puerto; solo si se
+	// configura GRPC_ADDR (p. ej. ":9090")
+	if addr := os.Getenv("GRPC_ADDR"); addr != "" {
+		go func() {
+			log.Println("✅ gRPC corriendo en " + addr)
+			log.Fatal(rpc.ListenAndServe(addr, rpc.NewServer(controllers.NewUserService(users))))
+		}()
+	}
 
 	log.Println("✅ Servidor corriendo en :8080")
 	log.Fatal(http.ListenAndServe(":8080", handlerWithCORS))
 }
+
+// Separa una lista por comas quitando espacios
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"api3/db"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+const migrateUsage = "uso: api-zoo migrate up | down [pasos] | status"
+
+// Subcomando `migrate`: gestiona las migraciones sin levantar el servidor
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	db.ConnectDB()
+	migrator, err := db.NewDefaultMigrator()
+	if err != nil {
+		log.Fatal("❌ Error al preparar migraciones:", err)
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("✅ Aplicada %d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal("❌ Error al migrar:", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Sin migraciones pendientes")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatal(migrateUsage)
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Printf("↩️  Revertida %d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal("❌ Error al revertir:", err)
+		}
+
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatal("❌ Error al leer migraciones:", err)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSIÓN\tNOMBRE\tESTADO\tAPLICADA")
+		for _, s := range status {
+			state, at := "pendiente", ""
+			if s.Applied {
+				state, at = "aplicada", s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, at)
+		}
+		tw.Flush()
+
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"api3/src/routes"
+	"encoding/json"
+	"log"
+	"os"
+)
+
+// Subcomando `openapi`: escribe la especificación de las rutas en la salida
+// estándar (api-zoo openapi > docs/openapi.json)
+func runOpenAPI() {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(routes.OpenAPI()); err != nil {
+		log.Fatal("❌ Error al escribir la especificación: ", err)
+	}
+}
@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"api3/src/events"
+	"api3/src/models"
+	"api3/src/problem"
+	"api3/src/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Eventos pendientes de enviar por suscriptor; si se llena se le desconecta
+const eventBuffer = 64
+
+var upgrader = websocket.Upgrader{
+	// El token va en la URL, como en el resto de la API, y CORS admite
+	// cualquier origen: el origen no añade nada
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// publish avisa del cambio a los suscriptores de eventos y a los webhooks.
+// Si no se pueden preparar las entregas el cambio sigue hecho: solo se avisa.
+// Las entregas se guardan aunque el cliente ya se haya desconectado.
+func (h *UserHandler) publish(ctx context.Context, typ string, user *models.User, prevZona string) {
+	if h.events == nil {
+		return
+	}
+	changed := *user
+	changed.FormatImageURL()
+	e := h.events.Publish(typ, changed, prevZona)
+	if h.webhooks != nil {
+		if err := h.webhooks.Enqueue(context.WithoutCancel(ctx), e); err != nil {
+			log.Printf("⚠️  No se pudieron preparar los webhooks del evento %d: %v", e.ID, err)
+		}
+	}
+}
+
+// StreamEvents envía los cambios de usuarios como Server-Sent Events. Con
+// Last-Event-ID (o ?lastEventId=) reanuda tras el último recibido.
+func (h *UserHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	sub, missed, complete, prob := h.subscribe(r, r.Header.Get("Last-Event-ID"))
+	if prob != nil {
+		problem.Write(w, r, prob)
+		return
+	}
+	defer sub.Close()
+	flusher, _ := w.(http.Flusher)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // sin búfer en nginx
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", utils.EnvDuration("EVENTS_RETRY", 3*time.Second).Milliseconds())
+	if !complete {
+		writeSSE(w, events.Event{ID: sub.LastID, Type: events.Reset, Time: time.Now().UTC()})
+	}
+	for _, e := range missed {
+		writeSSE(w, e)
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	keepalive := time.NewTicker(utils.EnvDuration("EVENTS_KEEPALIVE", 25*time.Second))
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			writeSSE(w, e)
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, e events.Event) {
+	data, _ := json.Marshal(e)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+}
+
+// EventsSocket envía los mismos eventos por WebSocket, uno por mensaje JSON;
+// para reanudar se usa ?lastEventId=
+func (h *UserHandler) EventsSocket(w http.ResponseWriter, r *http.Request) {
+	sub, missed, complete, prob := h.subscribe(r, "")
+	if prob != nil {
+		problem.Write(w, r, prob)
+		return
+	}
+	defer sub.Close()
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade ya respondió
+	}
+	defer conn.Close()
+
+	// Los mensajes del cliente no se usan, pero hay que leerlos para
+	// atender los pings y enterarse del cierre
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if !complete {
+		missed = append([]events.Event{{ID: sub.LastID, Type: events.Reset, Time: time.Now().UTC()}}, missed...)
+	}
+	for _, e := range missed {
+		if conn.WriteJSON(e) != nil {
+			return
+		}
+	}
+
+	keepalive := time.NewTicker(utils.EnvDuration("EVENTS_KEEPALIVE", 25*time.Second))
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-keepalive.C:
+			if conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second)) != nil {
+				return
+			}
+		case e, ok := <-sub.C:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "slow consumer"), time.Now().Add(time.Second))
+				return
+			}
+			if conn.WriteJSON(e) != nil {
+				return
+			}
+		}
+	}
+}
+
+// Suscribe según el token: un admin ve todas las zonas (o la de ?zona=) y
+// el resto solo la suya
+func (h *UserHandler) subscribe(r *http.Request, lastEventID string) (*events.Subscription, []events.Event, bool, *problem.Problem) {
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+	var lastID uint64
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			return nil, nil, false, invalidParam("lastEventId", problem.FieldInvalidValue)
+		}
+		lastID = id
+	}
+
+	claims, _ := utils.ClaimsFrom(r.Context())
+	scope := events.Scope{Zona: claims.Zona}
+	if strings.EqualFold(claims.Role, models.RoleAdmin) {
+		scope = events.Scope{Admin: true, Zona: r.URL.Query().Get("zona")}
+	}
+	sub, missed, complete := h.events.Subscribe(scope, lastID, eventBuffer)
+	return sub, missed, complete, nil
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"api3/src/events"
+	"api3/src/models"
+	"api3/src/repository"
+	"api3/src/webhooks"
+	"context"
+	"testing"
+	"time"
+)
+
+// cancelAwareHooks falla como la BD cuando el contexto ya está cancelado
+type cancelAwareHooks struct {
+	repository.WebhookRepository
+}
+
+func (h cancelAwareHooks) AddDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return h.WebhookRepository.AddDeliveries(ctx, deliveries)
+}
+
+// Un cliente que se desconecta tras guardar no deja el webhook sin entrega
+func TestPublishAfterDisconnect(t *testing.T) {
+	hooks := cancelAwareHooks{repository.NewMemoryWebhookRepository()}
+	if err := hooks.Create(context.Background(), &models.Webhook{URL: "http://ejemplo.com/hook", Secret: "secreto-de-prueba", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	h := newTestHandler(t)
+	h.webhooks = webhooks.NewDispatcher(hooks, webhooks.Config{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	h.publish(ctx, events.UserCreated, &models.User{ID: 1, Username: "ana"}, "")
+
+	due, err := hooks.ClaimDue(context.Background(), time.Now().Add(time.Second), time.Minute, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 || due[0].EventType != events.UserCreated {
+		t.Errorf("entregas %+v, se esperaba una de %s", due, events.UserCreated)
+	}
+}
@@ -0,0 +1,545 @@
+package controllers
+
+import (
+	"api3/src/gql"
+	"api3/src/models"
+	"api3/src/problem"
+	"api3/src/repository"
+	"api3/src/utils"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+)
+
+// Límites por defecto de /graphql; se cambian con GRAPHQL_MAX_DEPTH y
+// GRAPHQL_MAX_COMPLEXITY (0 los desactiva)
+const (
+	defaultGraphQLDepth      = 8
+	defaultGraphQLComplexity = 5000
+	defaultZoneUsers         = 10
+)
+
+// GraphQLHandler atiende /graphql: usuarios con su zona y su rol en una sola
+// petición. Los permisos salen del mismo token que en REST (?token=) y las
+// mutaciones usan la misma lógica y los mismos códigos de error.
+type GraphQLHandler struct {
+	users *UserHandler
+	zones repository.ZoneRepository
+	serve http.HandlerFunc
+}
+
+func NewGraphQLHandler(users *UserHandler, zones *ZoneHandler) (*GraphQLHandler, error) {
+	h := &GraphQLHandler{users: users, zones: zones.zones}
+	schema, err := h.schema()
+	if err != nil {
+		return nil, err
+	}
+	h.serve = gql.Handler(schema, gql.Limits{
+		MaxDepth:      utils.EnvInt("GRAPHQL_MAX_DEPTH", defaultGraphQLDepth),
+		MaxComplexity: utils.EnvInt("GRAPHQL_MAX_COMPLEXITY", defaultGraphQLComplexity),
+	})
+	return h, nil
+}
+
+// Serve ejecuta una operación GraphQL
+func (h *GraphQLHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	ctx := context.WithValue(r.Context(), zoneCacheKey{}, &zoneCache{zones: h.zones})
+	h.serve(w, r.WithContext(ctx))
+}
+
+func (h *GraphQLHandler) schema() (graphql.Schema, error) {
+	roleEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Role",
+		Values: graphql.EnumValueConfigMap{
+			"ADMIN": {Value: models.RoleAdmin, Description: "Gestiona usuarios y zonas"},
+			"USER":  {Value: models.RoleUser},
+		},
+	})
+	statusEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Status",
+		Values: graphql.EnumValueConfigMap{
+			"ACTIVE":    {Value: models.StatusActive},
+			"INACTIVE":  {Value: models.StatusInactive},
+			"SUSPENDED": {Value: models.StatusSuspended},
+		},
+	})
+
+	zoneType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Zone",
+		Description: "Zona del zoológico",
+		Fields: graphql.Fields{
+			"name":      {Type: graphql.NewNonNull(graphql.String)},
+			"createdAt": {Type: graphql.DateTime},
+		},
+	})
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":          {Type: graphql.NewNonNull(graphql.ID)},
+			"username":    {Type: graphql.NewNonNull(graphql.String)},
+			"displayName": {Type: graphql.NewNonNull(graphql.String)},
+			"role":        {Type: graphql.NewNonNull(roleEnum)},
+			"isAdmin": {
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return userFrom(p.Source).Role == models.RoleAdmin, nil
+				},
+			},
+			"status": {Type: graphql.NewNonNull(statusEnum)},
+			"zona":   {Type: graphql.NewNonNull(graphql.String)},
+			"zone": {
+				Type:        zoneType,
+				Description: "La zona del catálogo (null si ya no existe)",
+				Resolve:     resolver(h.userZone),
+			},
+			"imageUrl": {
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					user := *userFrom(p.Source)
+					user.FormatImageURL()
+					if user.ImageURL == "" {
+						return nil, nil
+					}
+					return user.ImageURL, nil
+				},
+			},
+			"version":   {Type: graphql.NewNonNull(graphql.Int)},
+			"updatedAt": {Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+	pageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "UserPage",
+		Fields: graphql.Fields{
+			"data": {
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*repository.UserPage).Users, nil
+				},
+			},
+			"total": {
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*repository.UserPage).Total, nil
+				},
+			},
+			"next": {
+				Type:        graphql.String,
+				Description: "Cursor de la página siguiente",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return encodeCursor(p.Source.(*repository.UserPage).Next), nil
+				},
+			},
+			"prev": {
+				Type:        graphql.String,
+				Description: "Cursor de la página anterior",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return encodeCursor(p.Source.(*repository.UserPage).Prev), nil
+				},
+			},
+		},
+	})
+	zoneType.AddFieldConfig("users", &graphql.Field{
+		Type:        graphql.NewNonNull(pageType),
+		Description: "Usuarios de la zona. Requiere rol admin.",
+		Args: graphql.FieldConfigArgument{
+			"limit":  {Type: graphql.Int, DefaultValue: defaultZoneUsers},
+			"offset": {Type: graphql.Int},
+		},
+		Resolve: resolver(h.zoneUsers),
+	})
+
+	filterInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "UserFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"role":     {Type: roleEnum},
+			"zona":     {Type: graphql.String},
+			"status":   {Type: statusEnum},
+			"username": {Type: graphql.String, Description: "Prefijo del nombre de usuario"},
+		},
+	})
+	registerInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "RegisterInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"username":    {Type: graphql.NewNonNull(graphql.String)},
+			"displayName": {Type: graphql.String},
+			"password":    {Type: graphql.NewNonNull(graphql.String)},
+			"role":        {Type: roleEnum},
+			"zona":        {Type: graphql.NewNonNull(graphql.String)},
+			"image":       {Type: graphql.String, Description: "Imagen de perfil en base64"},
+		},
+	})
+	updateInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "UpdateUserInput",
+		Description: "Campos a cambiar; los que no se envían (o van a null) quedan igual",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"username":    {Type: graphql.String},
+			"displayName": {Type: graphql.String},
+			"password":    {Type: graphql.String},
+			"role":        {Type: roleEnum},
+			"zona":        {Type: graphql.String},
+			"status":      {Type: statusEnum},
+			"image":       {Type: graphql.String, Description: "Imagen de perfil en base64"},
+			"removeImage": {Type: graphql.Boolean, Description: "true quita la imagen (no se combina con image)"},
+		},
+	})
+	versionArg := &graphql.ArgumentConfig{
+		Type:        graphql.Int,
+		Description: "Versión leída; si el usuario cambió desde entonces falla con precondition_failed",
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"users": {
+				Type:        graphql.NewNonNull(pageType),
+				Description: "Página de usuarios filtrada y ordenada, como GET /users. Requiere rol admin.",
+				Args: graphql.FieldConfigArgument{
+					"filter": {Type: filterInput},
+					"sort":   {Type: graphql.String, Description: "Columnas separadas por comas; con - delante, descendente"},
+					"limit":  {Type: graphql.Int, DefaultValue: defaultPageSize},
+					"offset": {Type: graphql.Int},
+					"cursor": {Type: graphql.String, Description: "Cursor de next o prev (no se combina con offset)"},
+				},
+				Resolve: resolver(h.listUsers),
+			},
+			"user": {
+				Type:        userType,
+				Description: "Un usuario por ID. Requiere rol admin.",
+				Args:        graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve:     resolver(h.getUser),
+			},
+			"me": {
+				Type:        graphql.NewNonNull(userType),
+				Description: "El usuario del token",
+				Resolve:     resolver(h.me),
+			},
+			"zones": {
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(zoneType))),
+				Description: "Las zonas válidas. Requiere token.",
+				Resolve:     resolver(h.listZones),
+			},
+		},
+	})
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"registerUser": {
+				Type:        graphql.NewNonNull(userType),
+				Description: "Crea un usuario, como POST /users. Crear un admin requiere rol admin.",
+				Args:        graphql.FieldConfigArgument{"input": {Type: graphql.NewNonNull(registerInput)}},
+				Resolve:     resolver(h.registerUser),
+			},
+			"updateUser": {
+				Type:        graphql.NewNonNull(userType),
+				Description: "Cambia los campos enviados, como PATCH /users/{id}. Requiere rol admin.",
+				Args: graphql.FieldConfigArgument{
+					"id":      {Type: graphql.NewNonNull(graphql.ID)},
+					"input":   {Type: graphql.NewNonNull(updateInput)},
+					"version": versionArg,
+				},
+				Resolve: resolver(h.updateUser),
+			},
+			"deleteUser": {
+				Type:        graphql.NewNonNull(graphql.Boolean),
+				Description: "Elimina un usuario, como DELETE /users/{id}. Requiere rol admin.",
+				Args: graphql.FieldConfigArgument{
+					"id":      {Type: graphql.NewNonNull(graphql.ID)},
+					"version": versionArg,
+				},
+				Resolve: resolver(h.deleteUser),
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+// resolver traduce los errores de fn a errores de GraphQL con su código
+func resolver(fn graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		result, err := fn(p)
+		if err != nil {
+			return nil, gql.Error(p.Context, err)
+		}
+		return result, nil
+	}
+}
+
+func (h *GraphQLHandler) listUsers(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireAdmin(p.Context); err != nil {
+		return nil, err
+	}
+	// Se pasan a parámetros de URL para aplicar las mismas reglas que GET /users
+	params := url.Values{}
+	if filter, ok := p.Args["filter"].(map[string]interface{}); ok {
+		for name, value := range filter {
+			if s, ok := value.(string); ok {
+				params.Set(name, s)
+			}
+		}
+	}
+	for _, name := range []string{"sort", "cursor"} {
+		if s, ok := p.Args[name].(string); ok {
+			params.Set(name, s)
+		}
+	}
+	for _, name := range []string{"limit", "offset"} {
+		if n, ok := p.Args[name].(int); ok {
+			params.Set(name, strconv.Itoa(n))
+		}
+	}
+	q, prob := parseListParams(params)
+	if prob != nil {
+		return nil, prob
+	}
+	return h.users.listUsers(p.Context, q)
+}
+
+func (h *GraphQLHandler) getUser(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireAdmin(p.Context); err != nil {
+		return nil, err
+	}
+	return h.userArg(p)
+}
+
+func (h *GraphQLHandler) me(p graphql.ResolveParams) (interface{}, error) {
+	claims, err := requireClaims(p.Context)
+	if err != nil {
+		return nil, err
+	}
+	user, err := h.users.users.GetByID(p.Context, int(claims.UserID))
+	if err != nil {
+		return nil, lookupProblem(err)
+	}
+	return user, nil
+}
+
+func (h *GraphQLHandler) listZones(p graphql.ResolveParams) (interface{}, error) {
+	if _, err := requireClaims(p.Context); err != nil {
+		return nil, err
+	}
+	zones, err := h.zones.List(p.Context)
+	if err != nil {
+		return nil, problem.Internal(err)
+	}
+	return zones, nil
+}
+
+func (h *GraphQLHandler) userZone(p graphql.ResolveParams) (interface{}, error) {
+	cache, ok := p.Context.Value(zoneCacheKey{}).(*zoneCache)
+	if !ok {
+		cache = &zoneCache{zones: h.zones}
+	}
+	zone, err := cache.get(p.Context, userFrom(p.Source).Zona)
+	if err != nil || zone == nil {
+		return nil, err
+	}
+	return zone, nil
+}
+
+func (h *GraphQLHandler) zoneUsers(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireAdmin(p.Context); err != nil {
+		return nil, err
+	}
+	params := url.Values{"zona": {zoneFrom(p.Source).Name}}
+	for _, name := range []string{"limit", "offset"} {
+		if n, ok := p.Args[name].(int); ok {
+			params.Set(name, strconv.Itoa(n))
+		}
+	}
+	q, prob := parseListParams(params)
+	if prob != nil {
+		return nil, prob
+	}
+	return h.users.listUsers(p.Context, q)
+}
+
+func (h *GraphQLHandler) registerUser(p graphql.ResolveParams) (interface{}, error) {
+	var input RegisterInput
+	data, err := json.Marshal(p.Args["input"])
+	if err == nil {
+		err = json.Unmarshal(data, &input)
+	}
+	if err != nil {
+		return nil, problem.Internal(err)
+	}
+	return h.users.createUser(p.Context, &input)
+}
+
+func (h *GraphQLHandler) updateUser(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireAdmin(p.Context); err != nil {
+		return nil, err
+	}
+	user, err := h.userForChange(p)
+	if err != nil {
+		return nil, err
+	}
+
+	// Se aplica como un merge patch; GraphQL descarta los null, así que
+	// quitar la imagen se pide con removeImage
+	input, _ := p.Args["input"].(map[string]interface{})
+	remove, _ := input["removeImage"].(bool)
+	delete(input, "removeImage")
+	fields := make(map[string]json.RawMessage, len(input))
+	for name, value := range input {
+		if fields[name], err = json.Marshal(value); err != nil {
+			return nil, problem.Internal(err)
+		}
+	}
+	_, withImage := fields["image"]
+	if remove && !withImage {
+		fields["image"] = json.RawMessage("null")
+	}
+	patch, errs := patchFromFields(fields)
+	if remove && withImage {
+		errs["removeImage"] = problem.FieldConflict
+	}
+	if prob := h.users.validateInput(p.Context, &patch, errs); prob != nil {
+		return nil, prob
+	}
+	if err := h.users.updateUser(p.Context, user, patch); err != nil {
+		return nil, h.changeFailed(p, user.ID, err)
+	}
+	return user, nil
+}
+
+func (h *GraphQLHandler) deleteUser(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireAdmin(p.Context); err != nil {
+		return nil, err
+	}
+	user, err := h.userForChange(p)
+	if err != nil {
+		return nil, err
+	}
+	// Sin version se borra aunque haya cambiado desde la lectura
+	var version int64
+	if _, ok := p.Args["version"].(int); ok {
+		version = user.Version
+	}
+	if err := h.users.deleteUser(p.Context, user, version); err != nil {
+		return nil, h.changeFailed(p, user.ID, err)
+	}
+	return true, nil
+}
+
+// Busca el usuario del argumento id
+func (h *GraphQLHandler) userArg(p graphql.ResolveParams) (*models.User, error) {
+	id, err := idArg(p)
+	if err != nil {
+		return nil, err
+	}
+	user, err := h.users.users.GetByID(p.Context, id)
+	if err != nil {
+		return nil, lookupProblem(err)
+	}
+	return user, nil
+}
+
+// Busca el usuario a modificar; el argumento version hace de If-Match
+func (h *GraphQLHandler) userForChange(p graphql.ResolveParams) (*models.User, error) {
+	id, err := idArg(p)
+	if err != nil {
+		return nil, err
+	}
+	var expected *int64
+	if version, ok := p.Args["version"].(int); ok {
+		v := int64(version)
+		expected = &v
+	}
+	return h.users.userForChange(p.Context, id, expected)
+}
+
+func (h *GraphQLHandler) changeFailed(p graphql.ResolveParams, id int, err error) error {
+	_, withVersion := p.Args["version"].(int)
+	return h.users.changeFailed(p.Context, id, withVersion, err)
+}
+
+func idArg(p graphql.ResolveParams) (int, error) {
+	raw, _ := p.Args["id"].(string)
+	id, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, problem.New(problem.CodeInvalidID)
+	}
+	return id, nil
+}
+
+// Datos del token; sin token es el mismo 401 que en REST
+func requireClaims(ctx context.Context) (*utils.Claims, error) {
+	claims, ok := utils.ClaimsFrom(ctx)
+	if !ok {
+		return nil, problem.New(problem.CodeTokenMissing)
+	}
+	return claims, nil
+}
+
+func requireAdmin(ctx context.Context) error {
+	claims, err := requireClaims(ctx)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(claims.Role, models.RoleAdmin) {
+		return problem.New(problem.CodeForbidden)
+	}
+	return nil
+}
+
+// Los usuarios llegan como puntero (consultas sueltas) o como valor (listas)
+func userFrom(source interface{}) *models.User {
+	if user, ok := source.(models.User); ok {
+		return &user
+	}
+	return source.(*models.User)
+}
+
+func zoneFrom(source interface{}) *models.Zone {
+	if zone, ok := source.(models.Zone); ok {
+		return &zone
+	}
+	return source.(*models.Zone)
+}
+
+func encodeCursor(c *repository.Cursor) interface{} {
+	if c == nil {
+		return nil
+	}
+	return c.Encode()
+}
+
+type zoneCacheKey struct{}
+
+// zoneCache carga el catálogo de zonas una vez por petición, para no
+// consultarlo por cada usuario de una lista
+type zoneCache struct {
+	zones  repository.ZoneRepository
+	once   sync.Once
+	byName map[string]models.Zone
+	err    error
+}
+
+// get devuelve la zona llamada name o nil si no está en el catálogo
+func (c *zoneCache) get(ctx context.Context, name string) (*models.Zone, error) {
+	c.once.Do(func() {
+		var zones []models.Zone
+		if zones, c.err = c.zones.List(ctx); c.err != nil {
+			return
+		}
+		c.byName = make(map[string]models.Zone, len(zones))
+		for _, zone := range zones {
+			c.byName[zone.Name] = zone
+		}
+	})
+	if c.err != nil {
+		return nil, problem.Internal(c.err)
+	}
+	zone, ok := c.byName[name]
+	if !ok {
+		return nil, nil
+	}
+	return &zone, nil
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"api3/src/models"
+	"api3/src/repository"
+	"api3/src/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// graphQLError es lo que interesa de un error en la respuesta
+type graphQLError struct {
+	Extensions struct {
+		Code string `json:"code"`
+	} `json:"extensions"`
+}
+
+// Permisos de las mutaciones: registerUser es público salvo para crear
+// admins; updateUser y deleteUser exigen rol admin
+func TestGraphQLMutationAuth(t *testing.T) {
+	admin := &utils.Claims{UserID: 1, Role: models.RoleAdmin}
+	user := &utils.Claims{UserID: 2, Role: models.RoleUser}
+
+	for _, tt := range []struct {
+		name   string
+		claims *utils.Claims
+		query  string
+		code   string // vacío: sin errores
+	}{
+		{"registro anónimo", nil, `mutation { registerUser(input: {username: "bea", password: "secreto123", zona: "sur"}) { id } }`, ""},
+		{"registro anónimo como user", nil, `mutation { registerUser(input: {username: "bea", password: "secreto123", zona: "sur", role: USER}) { id } }`, ""},
+		{"admin anónimo", nil, `mutation { registerUser(input: {username: "bea", password: "secreto123", zona: "sur", role: ADMIN}) { id } }`, "forbidden"},
+		{"admin creado por un user", user, `mutation { registerUser(input: {username: "bea", password: "secreto123", zona: "sur", role: ADMIN}) { id } }`, "forbidden"},
+		{"admin creado por un admin", admin, `mutation { registerUser(input: {username: "bea", password: "secreto123", zona: "sur", role: ADMIN}) { id role } }`, ""},
+		{"updateUser anónimo", nil, `mutation { updateUser(id: "ID", input: {zona: "sur"}) { id } }`, "token_missing"},
+		{"updateUser de un user", user, `mutation { updateUser(id: "ID", input: {zona: "sur"}) { id } }`, "forbidden"},
+		{"updateUser de un admin", admin, `mutation { updateUser(id: "ID", input: {zona: "sur"}) { id } }`, ""},
+		{"deleteUser anónimo", nil, `mutation { deleteUser(id: "ID") }`, "token_missing"},
+		{"deleteUser de un user", user, `mutation { deleteUser(id: "ID") }`, "forbidden"},
+		{"deleteUser de un admin", admin, `mutation { deleteUser(id: "ID") }`, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			users := newTestHandler(t)
+			id := register(t, users, "ana", "norte")
+			h, err := NewGraphQLHandler(users, NewZoneHandler(repository.NewMemoryZoneRepository()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			body, _ := json.Marshal(map[string]string{"query": strings.ReplaceAll(tt.query, "ID", strconv.Itoa(id))})
+			r := httptest.NewRequest("POST", "/graphql", strings.NewReader(string(body)))
+			r.Header.Set("Content-Type", "application/json")
+			if tt.claims != nil {
+				r = r.WithContext(utils.WithClaims(r.Context(), tt.claims))
+			}
+			rec := httptest.NewRecorder()
+			h.Serve(rec, r)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("estado %d: %s", rec.Code, rec.Body)
+			}
+
+			var resp struct {
+				Errors []graphQLError `json:"errors"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			code := ""
+			if len(resp.Errors) > 0 {
+				code = resp.Errors[0].Extensions.Code
+			}
+			if code != tt.code {
+				t.Fatalf("error %q, se esperaba %q: %s", code, tt.code, rec.Body)
+			}
+			if tt.code != "" && strings.Contains(tt.query, "registerUser") {
+				if _, err := users.users.GetByUsername(r.Context(), "bea"); err == nil {
+					t.Error("se creó el usuario")
+				}
+			}
+		})
+	}
+}
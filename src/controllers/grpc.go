@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"api3/src/models"
+	"api3/src/problem"
+	"api3/src/rpc/userpb"
+	"api3/src/utils"
+	"context"
+	"net/url"
+	"strconv"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// UserService atiende el servicio gRPC de usuarios con la misma lógica que
+// REST y GraphQL. Los permisos los aplica el interceptor de rpc.
+type UserService struct {
+	userpb.UnimplementedUserServiceServer
+	users *UserHandler
+}
+
+func NewUserService(users *UserHandler) *UserService {
+	return &UserService{users: users}
+}
+
+func (s *UserService) GetUser(ctx context.Context, req *userpb.GetUserRequest) (*userpb.User, error) {
+	var user *models.User
+	var err error
+	switch lookup := req.Lookup.(type) {
+	case *userpb.GetUserRequest_Id:
+		user, err = s.users.users.GetByID(ctx, int(lookup.Id))
+	case *userpb.GetUserRequest_Username:
+		user, err = s.users.users.GetByUsername(ctx, lookup.Username)
+	default:
+		return nil, problem.New(problem.CodeInvalidID)
+	}
+	if err != nil {
+		return nil, lookupProblem(err)
+	}
+	return toProto(user), nil
+}
+
+func (s *UserService) ListUsers(ctx context.Context, req *userpb.ListUsersRequest) (*userpb.ListUsersResponse, error) {
+	// Mismos parámetros que GET /users para aplicar las mismas reglas
+	params := url.Values{}
+	for name, value := range map[string]string{
+		"role": req.Role, "zona": req.Zona, "status": req.Status,
+		"username": req.UsernamePrefix, "sort": req.Sort, "cursor": req.Cursor,
+	} {
+		if value != "" {
+			params.Set(name, value)
+		}
+	}
+	if req.Limit != 0 {
+		params.Set("limit", strconv.Itoa(int(req.Limit)))
+	}
+	if req.Offset != 0 {
+		params.Set("offset", strconv.Itoa(int(req.Offset)))
+	}
+	q, prob := parseListParams(params)
+	if prob != nil {
+		return nil, prob
+	}
+	page, err := s.users.listUsers(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &userpb.ListUsersResponse{Total: page.Total}
+	for i := range page.Users {
+		resp.Users = append(resp.Users, toProto(&page.Users[i]))
+	}
+	if page.Next != nil {
+		resp.NextCursor = page.Next.Encode()
+	}
+	if page.Prev != nil {
+		resp.PrevCursor = page.Prev.Encode()
+	}
+	return resp, nil
+}
+
+// CreateUser es público para el alta normal; crear un admin exige un token
+// de admin (lo comprueba createUser)
+func (s *UserService) CreateUser(ctx context.Context, req *userpb.CreateUserRequest) (*userpb.User, error) {
+	input := &RegisterInput{
+		Username:    req.Username,
+		DisplayName: req.DisplayName,
+		Password:    req.Password,
+		Role:        req.Role,
+		Zona:        req.Zona,
+	}
+	if len(req.Image) > 0 {
+		input.setImageFile(req.Image)
+	}
+	user, err := s.users.createUser(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return toProto(user), nil
+}
+
+func (s *UserService) UpdateUser(ctx context.Context, req *userpb.UpdateUserRequest) (*userpb.User, error) {
+	p := UserPatch{
+		Username:    req.Username,
+		DisplayName: req.DisplayName,
+		Password:    req.Password,
+		Role:        req.Role,
+		Zona:        req.Zona,
+		Status:      req.Status,
+	}
+	switch {
+	case req.Image != nil && req.RemoveImage:
+		return nil, problem.New(problem.CodeValidation).WithFields(map[string]string{"image": problem.FieldConflict})
+	case req.Image != nil && len(req.Image) == 0:
+		return nil, problem.New(problem.CodeValidation).WithFields(map[string]string{"image": problem.FieldEmpty})
+	case req.Image != nil:
+		p.Image, p.ImageSet = req.Image, true
+	case req.RemoveImage:
+		p.ImageSet = true
+	}
+	if prob := s.users.validateInput(ctx, &p, nil); prob != nil {
+		return nil, prob
+	}
+
+	user, err := s.users.userForChange(ctx, int(req.Id), req.ExpectedVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.users.updateUser(ctx, user, p); err != nil {
+		return nil, s.users.changeFailed(ctx, user.ID, req.ExpectedVersion != nil, err)
+	}
+	return toProto(user), nil
+}
+
+func (s *UserService) DeleteUser(ctx context.Context, req *userpb.DeleteUserRequest) (*userpb.DeleteUserResponse, error) {
+	user, err := s.users.userForChange(ctx, int(req.Id), req.ExpectedVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.users.deleteUser(ctx, user, req.GetExpectedVersion()); err != nil {
+		return nil, s.users.changeFailed(ctx, user.ID, req.ExpectedVersion != nil, err)
+	}
+	return &userpb.DeleteUserResponse{}, nil
+}
+
+// ValidateToken no falla con un token inválido: responde valid=false
+func (s *UserService) ValidateToken(ctx context.Context, req *userpb.ValidateTokenRequest) (*userpb.ValidateTokenResponse, error) {
+	claims, err := utils.ValidateToken(req.Token)
+	if err != nil {
+		return &userpb.ValidateTokenResponse{}, nil
+	}
+	resp := &userpb.ValidateTokenResponse{
+		Valid:  true,
+		UserId: int64(claims.UserID),
+		Role:   claims.Role,
+		Zona:   claims.Zona,
+	}
+	if claims.ExpiresAt != nil {
+		resp.ExpiresAt = timestamppb.New(claims.ExpiresAt.Time)
+	}
+	return resp, nil
+}
+
+func toProto(user *models.User) *userpb.User {
+	user.FormatImageURL()
+	return &userpb.User{
+		Id:          int64(user.ID),
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		Role:        user.Role,
+		Zona:        user.Zona,
+		Status:      user.Status,
+		ImageUrl:    user.ImageURL,
+		Version:     user.Version,
+		UpdatedAt:   timestamppb.New(user.UpdatedAt),
+	}
+}
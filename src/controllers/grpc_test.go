@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"api3/src/models"
+	"api3/src/problem"
+	"api3/src/rpc/userpb"
+	"api3/src/utils"
+	"context"
+	"errors"
+	"testing"
+)
+
+// CreateUser es público, pero solo un admin puede crear otro admin
+func TestGRPCCreateUserRole(t *testing.T) {
+	s := NewUserService(newTestHandler(t))
+	admin := utils.WithClaims(context.Background(), &utils.Claims{UserID: 1, Role: models.RoleAdmin})
+	user := utils.WithClaims(context.Background(), &utils.Claims{UserID: 2, Role: models.RoleUser})
+
+	for _, tt := range []struct {
+		name     string
+		ctx      context.Context
+		username string
+		role     string
+		code     string
+	}{
+		{"anónimo sin rol", context.Background(), "ana", "", ""},
+		{"anónimo como user", context.Background(), "bea", models.RoleUser, ""},
+		{"anónimo como admin", context.Background(), "carla", models.RoleAdmin, problem.CodeForbidden},
+		{"user crea un admin", user, "dora", models.RoleAdmin, problem.CodeForbidden},
+		{"admin crea un admin", admin, "eva", models.RoleAdmin, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			created, err := s.CreateUser(tt.ctx, &userpb.CreateUserRequest{Username: tt.username, Password: "secreto123", Role: tt.role, Zona: "norte"})
+			if tt.code == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tt.role != "" && created.Role != tt.role {
+					t.Errorf("rol %q, se esperaba %q", created.Role, tt.role)
+				}
+				return
+			}
+			var prob *problem.Problem
+			if !errors.As(err, &prob) || prob.Code != tt.code {
+				t.Fatalf("error %v, se esperaba %s", err, tt.code)
+			}
+			if _, err := s.users.users.GetByUsername(context.Background(), tt.username); err == nil {
+				t.Error("se creó el usuario")
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"api3/db"
+	"encoding/json"
+	"net/http"
+)
+
+// HealthHandler expone las sondas de liveness y readiness
+type HealthHandler struct {
+	dbHealth      func() db.HealthStatus
+	replicaHealth func() []db.HealthStatus
+}
+
+// ReadyResponse es el estado que informa la sonda de readiness
+type ReadyResponse struct {
+	Status   string            `json:"status"`
+	Database db.HealthStatus   `json:"database"`
+	Replicas []db.HealthStatus `json:"replicas"`
+}
+
+func NewHealthHandler(dbHealth func() db.HealthStatus, replicaHealth func() []db.HealthStatus) *HealthHandler {
+	return &HealthHandler{dbHealth: dbHealth, replicaHealth: replicaHealth}
+}
+
+// Live responde mientras el proceso esté vivo; no consulta la BD
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// Ready informa si la BD principal responde; las réplicas caídas solo se
+// informan
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	dbStatus := h.dbHealth()
+
+	status, code := "ok", http.StatusOK
+	if dbStatus.Status != db.StatusUp {
+		status, code = "unavailable", http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(ReadyResponse{Status: status, Database: dbStatus, Replicas: h.replicaHealth()})
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"api3/src/images"
+	"api3/src/models"
+	"api3/src/problem"
+	"api3/src/repository"
+	"api3/src/storage"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// GetUserImage sirve la imagen de perfil con ETag y peticiones Range
+func (h *UserHandler) GetUserImage(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, r, problem.New(problem.CodeInvalidID))
+		return
+	}
+	size := r.URL.Query().Get("size")
+	if size == "" {
+		size = images.SizeOriginal
+	}
+	if !images.ValidSize(size) {
+		problem.Write(w, r, problem.New(problem.CodeInvalidQuery).WithDetail("detail.image_sizes", images.SizeSmall, images.SizeMedium, images.SizeOriginal).WithFields(map[string]string{"size": problem.FieldInvalidValue}))
+		return
+	}
+
+	user, err := h.users.GetByID(r.Context(), id)
+	if err != nil {
+		problem.Write(w, r, lookupProblem(err))
+		return
+	}
+	if user.ImageKey == "" {
+		problem.Write(w, r, problem.New(problem.CodeImageNotFound))
+		return
+	}
+
+	blob, size, err := h.imageStore.Open(r.Context(), user.ImageKey, size)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			problem.Write(w, r, problem.New(problem.CodeImageNotFound))
+			return
+		}
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+	defer blob.Close()
+
+	content, err := seekable(blob)
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	version := user.ImageVersion()
+	w.Header().Set("ETag", `"`+version+"-"+size+`"`)
+	// Con ?v= la URL identifica un contenido que nunca cambia
+	if r.URL.Query().Get("v") == version {
+		w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "private, no-cache")
+	}
+
+	// ServeContent resuelve If-None-Match, Range y el Content-Type
+	http.ServeContent(w, r, "", time.Time{}, content)
+}
+
+// ServeContent necesita poder hacer Seek; los archivos locales ya lo
+// permiten, el resto se carga en memoria
+func seekable(blob io.ReadCloser) (io.ReadSeeker, error) {
+	if rs, ok := blob.(io.ReadSeeker); ok {
+		return rs, nil
+	}
+	data, err := io.ReadAll(blob)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// Con ?image=inline se mantiene el formato anterior (base64 en el JSON);
+// por defecto solo se devuelve la URL de la imagen
+func (h *UserHandler) formatImage(r *http.Request, user *models.User) {
+	if r.URL.Query().Get("image") == "inline" {
+		h.loadImage(r.Context(), user)
+		user.FormatImage()
+		return
+	}
+	user.FormatImageURL()
+}
+
+// Valida la imagen y la guarda (una sola vez por contenido); devuelve su clave
+func (h *UserHandler) storeImage(ctx context.Context, image []byte) (string, error) {
+	return h.imageStore.Save(ctx, image)
+}
+
+// Problema con el código adecuado según por qué se rechazó la imagen
+func imageProblem(err error) *problem.Problem {
+	switch {
+	case errors.Is(err, images.ErrUnsupportedFormat):
+		return problem.New(problem.CodeImageUnsupported).WithDetail("detail.image_formats")
+	case errors.Is(err, images.ErrTooLarge), errors.Is(err, images.ErrTooManyPixels):
+		return problem.New(problem.CodeImageTooLarge).WithDetail("detail.image_limits", images.MaxBytes>>20, images.MaxDimension)
+	case errors.Is(err, images.ErrInvalidImage):
+		return problem.New(problem.CodeImageInvalid)
+	default:
+		return problem.Internal(err)
+	}
+}
+
+// Problema de una búsqueda de usuario fallida
+func lookupProblem(err error) *problem.Problem {
+	if errors.Is(err, repository.ErrNotFound) {
+		return problem.New(problem.CodeUserNotFound)
+	}
+	return problem.Internal(err)
+}
+
+// Carga los bytes de la imagen para FormatImage; una imagen perdida no
+// impide devolver el usuario
+func (h *UserHandler) loadImage(ctx context.Context, user *models.User) {
+	if user.ImageKey == "" {
+		return
+	}
+	rc, _, err := h.imageStore.Open(ctx, user.ImageKey, images.SizeOriginal)
+	if err == nil {
+		defer rc.Close()
+		user.Image, err = io.ReadAll(rc)
+	}
+	if err != nil {
+		log.Printf("⚠️  No se pudo leer la imagen %s del usuario %d: %v", user.ImageKey, user.ID, err)
+		user.Image = nil
+	}
+}
+
+// Suelta la referencia a una imagen que el usuario ya no usa; un fallo solo
+// deja un huérfano para el recolector
+func (h *UserHandler) deleteImage(key string) {
+	if err := h.imageStore.Release(context.Background(), key); err != nil {
+		log.Printf("⚠️  No se pudo liberar la imagen %s: %v", key, err)
+	}
+}
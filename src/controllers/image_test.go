@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"api3/src/problem"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGetUserImageBadSize(t *testing.T) {
+	h := newTestHandler(t)
+	id := register(t, h, "ana", "norte")
+
+	rec := serve(h.GetUserImage, request{method: "GET", target: "/api/v1/users/" + strconv.Itoa(id) + "/image?size=512", vars: userVars(id), headers: []string{"Accept-Language", "en"}})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("estado %d: %s", rec.Code, rec.Body)
+	}
+	prob := decodeProblem(t, rec)
+	if prob.Code != problem.CodeInvalidQuery || fieldCodes(prob)["size"] != problem.FieldInvalidValue {
+		t.Errorf("problema %+v", prob)
+	}
+	if prob.Detail != "Available sizes: 64, 256 or original" {
+		t.Errorf("detalle %q", prob.Detail)
+	}
+}
+
+// registerWithImage crea un usuario con una imagen PNG de 8x8 y devuelve su
+// ID y la versión de la imagen
+func registerWithImage(t *testing.T, h *UserHandler, username string) (int, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	body := `{"username":"` + username + `","password":"secreto123","zona":"norte","image":"` + base64.StdEncoding.EncodeToString(buf.Bytes()) + `"}`
+	rec := serve(h.Register, request{method: "POST", target: "/api/v1/users", body: body})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("registrar %s: %d %s", username, rec.Code, rec.Body)
+	}
+	id, _ := strconv.Atoi(strings.TrimPrefix(rec.Header().Get("Location"), "/api/v1/users/"))
+	user, err := h.users.GetByID(context.Background(), id)
+	if err != nil || user.ImageVersion() == "" {
+		t.Fatalf("usuario %+v sin imagen: %v", user, err)
+	}
+	return id, user.ImageVersion()
+}
+
+func TestGetUserImageCaching(t *testing.T) {
+	h := newTestHandler(t)
+	id, version := registerWithImage(t, h, "ana")
+	etag := `"` + version + `-original"`
+	target := "/api/v1/users/" + strconv.Itoa(id) + "/image"
+
+	for _, tt := range []struct {
+		name         string
+		query        string
+		headers      []string
+		status       int
+		cacheControl string
+	}{
+		{"sin versión", "", nil, http.StatusOK, "private, no-cache"},
+		{"versión vigente", "?v=" + version, nil, http.StatusOK, "private, max-age=31536000, immutable"},
+		{"versión vieja", "?v=otra", nil, http.StatusOK, "private, no-cache"},
+		{"If-None-Match vigente", "", []string{"If-None-Match", etag}, http.StatusNotModified, "private, no-cache"},
+		{"If-None-Match viejo", "", []string{"If-None-Match", `"otra-original"`}, http.StatusOK, "private, no-cache"},
+		{"Range", "", []string{"Range", "bytes=0-3"}, http.StatusPartialContent, "private, no-cache"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(h.GetUserImage, request{method: "GET", target: target + tt.query, vars: userVars(id), headers: tt.headers})
+			if rec.Code != tt.status {
+				t.Fatalf("estado %d, se esperaba %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if got := rec.Header().Get("ETag"); got != etag {
+				t.Errorf("ETag %q, se esperaba %q", got, etag)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != tt.cacheControl {
+				t.Errorf("Cache-Control %q, se esperaba %q", got, tt.cacheControl)
+			}
+			switch tt.status {
+			case http.StatusNotModified:
+				if rec.Body.Len() != 0 {
+					t.Errorf("el 304 trae %d bytes", rec.Body.Len())
+				}
+			case http.StatusPartialContent:
+				if rec.Body.Len() != 4 || !strings.HasPrefix(rec.Header().Get("Content-Range"), "bytes 0-3/") {
+					t.Errorf("%d bytes, Content-Range %q", rec.Body.Len(), rec.Header().Get("Content-Range"))
+				}
+			default:
+				if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "image/") {
+					t.Errorf("Content-Type %q", ct)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,583 @@
+package controllers
+
+import (
+	"api3/src/events"
+	"api3/src/i18n"
+	"api3/src/models"
+	"api3/src/problem"
+	"api3/src/repository"
+	"api3/src/utils"
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	importMaxBytes     = 5 << 20
+	defaultImportChunk = 100
+	maxImportChunk     = 1000
+
+	formatCSV   = "csv"
+	formatJSONL = "jsonl"
+
+	passwordsGenerate = "generate"
+	passwordsInvite   = "invite"
+)
+
+// Resultado de cada fila de una importación
+const (
+	importCreated   = "created"
+	importUpdated   = "updated"
+	importUnchanged = "unchanged"
+	importInvalid   = "invalid"
+	importSkipped   = "skipped"
+	importFailed    = "failed"
+)
+
+// Columnas aceptadas en el CSV (sin distinguir mayúsculas) y su clave en
+// JSON Lines
+var importColumns = map[string]string{
+	"username":     "username",
+	"displayname":  "displayName",
+	"display_name": "displayName",
+	"password":     "password",
+	"role":         "role",
+	"zona":         "zona",
+	"status":       "status",
+}
+
+// Con dry_run la transacción se deshace al final a propósito
+var errDryRun = errors.New("simulación")
+
+// ImportReport es la respuesta de POST /users/import
+type ImportReport struct {
+	DryRun bool `json:"dryRun"`
+	Atomic bool `json:"atomic"`
+	// Committed indica si los cambios quedaron guardados
+	Committed bool              `json:"committed"`
+	Total     int               `json:"total"`
+	Created   int               `json:"created"`
+	Updated   int               `json:"updated"`
+	Unchanged int               `json:"unchanged"`
+	Invalid   int               `json:"invalid"`
+	Failed    int               `json:"failed"`
+	Rows      []ImportRowResult `json:"rows"`
+}
+
+// ImportRowResult es el resultado de una fila. Password (contraseña
+// generada) e Invitation (token para /auth/invitations/accept) solo se
+// devuelven esta vez.
+type ImportRowResult struct {
+	Line       int                  `json:"line"`
+	Username   string               `json:"username,omitempty"`
+	Action     string               `json:"action"`
+	Errors     []problem.FieldError `json:"errors,omitempty"`
+	Password   string               `json:"password,omitempty"`
+	Invitation string               `json:"invitation,omitempty"`
+}
+
+type importOptions struct {
+	dryRun    bool
+	atomic    bool
+	chunk     int
+	passwords string
+}
+
+// Fila leída del archivo; errs tiene los errores de lectura (p. ej. JSON
+// mal formado)
+type importRecord struct {
+	line  int
+	input ImportRowInput
+	errs  []problem.FieldError
+}
+
+// Contraseña preparada fuera de la transacción (bcrypt es lento). current
+// es el hash del usuario existente si ya coincide con password.
+type importSecret struct {
+	password string
+	hash     string
+	current  string
+}
+
+// ImportUsers crea o actualiza usuarios desde CSV o JSON Lines y devuelve
+// el resultado de cada fila
+func (h *UserHandler) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	opts, prob := parseImportOptions(r)
+	if prob != nil {
+		problem.Write(w, r, prob)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, importMaxBytes)
+	records, prob := readImport(r)
+	if prob != nil {
+		problem.Write(w, r, prob)
+		return
+	}
+
+	report := ImportReport{DryRun: opts.dryRun, Atomic: opts.atomic, Total: len(records), Rows: make([]ImportRowResult, len(records))}
+	seen := map[string]bool{}
+	var valid []int
+	for i := range records {
+		rec := &records[i]
+		res := &report.Rows[i]
+		res.Line, res.Username = rec.line, rec.input.Username
+
+		errs := rec.errs
+		if errs == nil {
+			if prob := h.validateInput(r.Context(), &rec.input, nil); prob != nil {
+				if prob.Code != problem.CodeValidation {
+					problem.Write(w, r, prob)
+					return
+				}
+				errs = prob.Errors
+			}
+		}
+		if errs == nil && seen[rec.input.Username] {
+			errs = []problem.FieldError{problem.Field("username", problem.FieldDuplicate)}
+		}
+		seen[rec.input.Username] = true
+		if errs != nil {
+			res.Action, res.Errors = importInvalid, errs
+			report.Invalid++
+			continue
+		}
+		valid = append(valid, i)
+	}
+
+	// Con atomic una fila inválida cancela todo; sin él se guardan las demás
+	if opts.atomic && report.Invalid > 0 {
+		for _, i := range valid {
+			report.Rows[i].Action = importSkipped
+		}
+	} else {
+		size := opts.chunk
+		if opts.atomic {
+			size = len(valid)
+		}
+		for start := 0; start < len(valid); start += size {
+			end := min(start+size, len(valid))
+			if err := h.importChunk(r.Context(), records, report.Rows, valid[start:end], opts); err != nil {
+				problem.Write(w, r, problem.Internal(err))
+				return
+			}
+		}
+	}
+
+	locale := i18n.FromRequest(r)
+	for i := range report.Rows {
+		res := &report.Rows[i]
+		problem.Localize(locale, res.Errors)
+		switch res.Action {
+		case importCreated:
+			report.Created++
+		case importUpdated:
+			report.Updated++
+		case importUnchanged:
+			report.Unchanged++
+		case importFailed:
+			report.Failed++
+		}
+	}
+	report.Committed = !opts.dryRun && !(opts.atomic && report.Invalid+report.Failed > 0)
+
+	status := http.StatusOK
+	if opts.atomic && !opts.dryRun && !report.Committed {
+		status = http.StatusUnprocessableEntity
+	}
+	i18n.SetHeaders(w, locale)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// Guarda un grupo de filas válidas en una transacción. Si una falla se
+// deshace el grupo entero y sus filas quedan como failed; solo devuelve
+// error si no se pudieron preparar las contraseñas.
+func (h *UserHandler) importChunk(ctx context.Context, records []importRecord, results []ImportRowResult, rows []int, opts importOptions) error {
+	secrets := make(map[int]importSecret, len(rows))
+	for _, i := range rows {
+		secret, err := h.prepareSecret(ctx, records[i].input, opts)
+		if err != nil {
+			return err
+		}
+		secrets[i] = secret
+	}
+
+	failedRow := -1
+	var changes []userChange
+	err := h.users.Transaction(ctx, func(tx repository.UserRepository) error {
+		changes = changes[:0]
+		for _, i := range rows {
+			if err := importRow(ctx, tx, records[i].input, secrets[i], opts, &results[i], &changes); err != nil {
+				failedRow = i
+				return err
+			}
+		}
+		if opts.dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err == nil {
+		// Se avisa solo de lo que quedó guardado
+		for _, c := range changes {
+			h.publish(ctx, c.typ, &c.user, c.prevZona)
+		}
+	}
+	if err == nil || errors.Is(err, errDryRun) {
+		return nil
+	}
+
+	for _, i := range rows {
+		results[i].Action, results[i].Password, results[i].Invitation = importFailed, "", ""
+	}
+	if failedRow >= 0 {
+		switch {
+		case errors.Is(err, repository.ErrDuplicate), errors.Is(err, repository.ErrVersionConflict):
+			results[failedRow].Errors = []problem.FieldError{problem.Field("username", problem.FieldConflict)}
+		default:
+			log.Printf("❌ Importación, línea %d: %v", records[failedRow].line, err)
+		}
+	}
+	return nil
+}
+
+// Hashea la contraseña de la fila o, si el usuario es nuevo y no trae una,
+// genera otra. En una simulación no se hashea nada.
+func (h *UserHandler) prepareSecret(ctx context.Context, in ImportRowInput, opts importOptions) (importSecret, error) {
+	secret := importSecret{password: in.Password}
+	if secret.password == "" {
+		if opts.passwords != passwordsGenerate {
+			return secret, nil
+		}
+		if _, err := h.users.GetByUsername(ctx, in.Username); !errors.Is(err, repository.ErrNotFound) {
+			// Ya existe (o falló la consulta y lo resolverá la transacción)
+			return secret, nil
+		}
+		password, err := generatePassword()
+		if err != nil {
+			return secret, err
+		}
+		secret.password = password
+	} else if existing, err := h.users.GetByUsername(ctx, in.Username); err == nil &&
+		bcrypt.CompareHashAndPassword([]byte(existing.Password), []byte(secret.password)) == nil {
+		// Ya tiene esa contraseña: volver a hashearla daría otra sal y
+		// contaría como cambio
+		secret.current = existing.Password
+		return secret, nil
+	}
+	if opts.dryRun {
+		return secret, nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret.password), bcrypt.DefaultCost)
+	if err != nil {
+		return secret, err
+	}
+	secret.hash = string(hash)
+	return secret, nil
+}
+
+// userChange es un cambio hecho en la transacción, pendiente de avisar
+type userChange struct {
+	typ      string
+	user     models.User
+	prevZona string
+}
+
+// Crea o actualiza el usuario de una fila dentro de la transacción
+func importRow(ctx context.Context, tx repository.UserRepository, in ImportRowInput, secret importSecret, opts importOptions, res *ImportRowResult, changes *[]userChange) error {
+	existing, err := tx.GetByUsername(ctx, in.Username)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	if existing != nil {
+		prevZona := existing.Zona
+		changed := false
+		for _, f := range []struct {
+			value string
+			dst   *string
+		}{{in.DisplayName, &existing.DisplayName}, {in.Role, &existing.Role}, {in.Zona, &existing.Zona}, {in.Status, &existing.Status}} {
+			if f.value != "" && f.value != *f.dst {
+				*f.dst, changed = f.value, true
+			}
+		}
+		if in.Password != "" && (secret.current == "" || secret.current != existing.Password) {
+			changed = true
+			if !opts.dryRun && secret.hash == "" {
+				// Coincidía fuera de la transacción pero la cambiaron después
+				hash, err := bcrypt.GenerateFromPassword([]byte(in.Password), bcrypt.DefaultCost)
+				if err != nil {
+					return err
+				}
+				secret.hash = string(hash)
+			}
+			if !opts.dryRun {
+				existing.Password = secret.hash
+			}
+		}
+		if !changed {
+			res.Action = importUnchanged
+			return nil
+		}
+		if err := tx.Update(ctx, existing); err != nil {
+			return err
+		}
+		res.Action = importUpdated
+		*changes = append(*changes, userChange{typ: events.UserUpdated, user: *existing, prevZona: prevZona})
+		return nil
+	}
+
+	user := models.User{Username: in.Username, DisplayName: in.DisplayName, Role: in.Role, Zona: in.Zona, Status: in.Status, Password: secret.hash}
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	invite := secret.password == "" && opts.passwords == passwordsInvite
+	if user.Status == "" {
+		// Un invitado queda inactivo y sin contraseña (bcrypt rechaza el
+		// hash vacío) hasta que acepta la invitación
+		user.Status = models.StatusActive
+		if invite {
+			user.Status = models.StatusInactive
+		}
+	}
+	if secret.password == "" && !invite && !opts.dryRun {
+		// Se creó entre la consulta previa y la transacción
+		password, err := generatePassword()
+		if err != nil {
+			return err
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		secret.password, user.Password = password, string(hash)
+	}
+	if err := tx.Create(ctx, &user); err != nil {
+		return err
+	}
+	res.Action = importCreated
+	*changes = append(*changes, userChange{typ: events.UserCreated, user: user})
+	if opts.dryRun {
+		return nil
+	}
+	if invite {
+		token, err := utils.GenerateInvitationToken(uint(user.ID), user.Version, utils.EnvDuration("INVITATION_TTL", 7*24*time.Hour))
+		if err != nil {
+			return err
+		}
+		res.Invitation = token
+	} else if in.Password == "" {
+		res.Password = secret.password
+	}
+	return nil
+}
+
+func parseImportOptions(r *http.Request) (importOptions, *problem.Problem) {
+	params := r.URL.Query()
+	opts := importOptions{chunk: defaultImportChunk, passwords: passwordsGenerate}
+
+	for param, dst := range map[string]*bool{"dry_run": &opts.dryRun, "atomic": &opts.atomic} {
+		if raw := params.Get(param); raw != "" {
+			value, err := strconv.ParseBool(raw)
+			if err != nil {
+				return opts, invalidParam(param, problem.FieldInvalidValue)
+			}
+			*dst = value
+		}
+	}
+	if raw := params.Get("chunk"); raw != "" {
+		chunk, err := strconv.Atoi(raw)
+		if err != nil || chunk < 1 || chunk > maxImportChunk {
+			return opts, invalidParam("chunk", problem.FieldOutOfRange).WithDetail("detail.import_chunk", maxImportChunk)
+		}
+		opts.chunk = chunk
+	}
+	if raw := params.Get("passwords"); raw != "" {
+		if raw != passwordsGenerate && raw != passwordsInvite {
+			return opts, invalidParam("passwords", problem.FieldInvalidValue)
+		}
+		opts.passwords = raw
+	}
+	if raw := params.Get("format"); raw != "" && raw != formatCSV && raw != formatJSONL {
+		return opts, invalidParam("format", problem.FieldInvalidValue).WithDetail("detail.import_formats")
+	}
+	return opts, nil
+}
+
+// Lee las filas del cuerpo o del archivo "file" de un multipart
+func readImport(r *http.Request) ([]importRecord, *problem.Problem) {
+	format := r.URL.Query().Get("format")
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var body io.Reader = r.Body
+
+	if mediaType == "multipart/form-data" {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return nil, problem.New(problem.CodeInvalidBody).WithDetail("detail.malformed_form", err.Error())
+		}
+		defer file.Close()
+		body = file
+		if format == "" {
+			partType, _, _ := mime.ParseMediaType(header.Header.Get("Content-Type"))
+			format = importFormat(partType, header.Filename)
+		}
+	} else if format == "" {
+		format = importFormat(mediaType, "")
+	}
+
+	var records []importRecord
+	var prob *problem.Problem
+	switch format {
+	case formatCSV:
+		records, prob = readCSV(body)
+	case formatJSONL:
+		records, prob = readJSONL(body)
+	default:
+		return nil, problem.New(problem.CodeUnsupportedMedia).WithDetail("detail.import_formats")
+	}
+	if prob == nil && len(records) == 0 {
+		prob = problem.New(problem.CodeImportInvalid).WithDetail("detail.import_empty")
+	}
+	return records, prob
+}
+
+func importFormat(mediaType, filename string) string {
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return formatCSV
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines", "application/json-lines":
+		return formatJSONL
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return formatCSV
+	case ".jsonl", ".ndjson":
+		return formatJSONL
+	}
+	return ""
+}
+
+// El CSV lleva una cabecera con los nombres de las columnas
+func readCSV(body io.Reader) ([]importRecord, *problem.Problem) {
+	cr := csv.NewReader(body)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, readProblem(1, err)
+	}
+	var unknown []string
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		key, ok := importColumns[name]
+		if !ok {
+			unknown = append(unknown, name)
+		}
+		header[i] = key
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, problem.New(problem.CodeImportInvalid).WithDetail("detail.import_columns", strings.Join(unknown, ", "))
+	}
+
+	maxRows := utils.EnvInt("IMPORT_MAX_ROWS", 5000)
+	var records []importRecord
+	for {
+		fields, err := cr.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		line, _ := cr.FieldPos(0)
+		if err != nil {
+			return nil, readProblem(line, err)
+		}
+		if len(records) == maxRows {
+			return nil, problem.New(problem.CodeImportInvalid).WithDetail("detail.import_max_rows", maxRows)
+		}
+
+		rec := importRecord{line: line}
+		if len(fields) != len(header) {
+			rec.errs = []problem.FieldError{problem.Field("columns", problem.FieldInvalidValue)}
+		} else {
+			values := url.Values{}
+			for i, name := range header {
+				values.Set(name, strings.TrimSpace(fields[i]))
+			}
+			if err := decodeForm(values, &rec.input); err != nil {
+				return nil, readProblem(line, err)
+			}
+		}
+		records = append(records, rec)
+	}
+}
+
+// Un objeto JSON por línea; las líneas en blanco se ignoran
+func readJSONL(body io.Reader) ([]importRecord, *problem.Problem) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64<<10), importMaxBytes)
+
+	maxRows := utils.EnvInt("IMPORT_MAX_ROWS", 5000)
+	var records []importRecord
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if len(records) == maxRows {
+			return nil, problem.New(problem.CodeImportInvalid).WithDetail("detail.import_max_rows", maxRows)
+		}
+
+		rec := importRecord{line: line}
+		if err := json.Unmarshal([]byte(text), &rec.input); err != nil {
+			rec.input = ImportRowInput{}
+			rec.errs = []problem.FieldError{problem.Field("line", problem.FieldInvalidJSON)}
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, readProblem(line+1, err)
+	}
+	return records, nil
+}
+
+func readProblem(line int, err error) *problem.Problem {
+	return problem.New(problem.CodeImportInvalid).WithDetail("detail.import_read", line, err.Error())
+}
+
+// Sin caracteres que se confunden (0/O, 1/l/I)
+const passwordAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789"
+
+func generatePassword() (string, error) {
+	password := make([]byte, 16)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(passwordAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		password[i] = passwordAlphabet[n.Int64()]
+	}
+	return string(password), nil
+}
@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"api3/src/models"
+	"api3/src/problem"
+	"api3/src/repository"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Reimportar la misma contraseña no cuenta como cambio
+func TestImportPasswordUnchanged(t *testing.T) {
+	h := newTestHandler(t)
+	for _, tt := range []struct {
+		name     string
+		query    string
+		password string
+		action   string
+	}{
+		{"nuevo", "", "secreto123", importCreated},
+		{"misma contraseña", "", "secreto123", importUnchanged},
+		{"misma contraseña en simulación", "?dry_run=true", "secreto123", importUnchanged},
+		{"otra contraseña en simulación", "?dry_run=true", "otra-clave", importUpdated},
+		{"otra contraseña", "", "otra-clave", importUpdated},
+		{"de nuevo la misma", "", "otra-clave", importUnchanged},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			body := "username,password,zona\nana," + tt.password + ",norte\n"
+			rec := serve(h.ImportUsers, request{method: "POST", target: "/api/v1/users/import" + tt.query, body: body, headers: []string{"Content-Type", "text/csv"}})
+			if rec.Code != http.StatusOK {
+				t.Fatalf("estado %d: %s", rec.Code, rec.Body)
+			}
+			var report ImportReport
+			if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+				t.Fatal(err)
+			}
+			if len(report.Rows) != 1 || report.Rows[0].Action != tt.action {
+				t.Fatalf("filas %+v, se esperaba %s", report.Rows, tt.action)
+			}
+		})
+	}
+
+	user, err := h.users.GetByUsername(context.Background(), "ana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Version != 2 || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("otra-clave")) != nil {
+		t.Errorf("versión %d; la contraseña guardada no es la última importada", user.Version)
+	}
+}
+
+// failingUsers hace fallar dentro de la transacción el alta de un usuario,
+// como una restricción de la BD
+type failingUsers struct {
+	repository.UserRepository
+	username string
+}
+
+func (f failingUsers) Create(ctx context.Context, user *models.User) error {
+	if user.Username == f.username {
+		return repository.ErrDuplicate
+	}
+	return f.UserRepository.Create(ctx, user)
+}
+
+func (f failingUsers) Transaction(ctx context.Context, fn func(tx repository.UserRepository) error) error {
+	return f.UserRepository.Transaction(ctx, func(tx repository.UserRepository) error {
+		return fn(failingUsers{tx, f.username})
+	})
+}
+
+// postImport envía un archivo a ImportUsers y decodifica el informe
+func postImport(t *testing.T, h *UserHandler, query, contentType, body string) (int, ImportReport) {
+	t.Helper()
+	rec := serve(h.ImportUsers, request{method: "POST", target: "/api/v1/users/import" + query, body: body, headers: []string{"Content-Type", contentType}})
+	var report ImportReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("%d %s: %v", rec.Code, rec.Body, err)
+	}
+	return rec.Code, report
+}
+
+func TestImportUsers(t *testing.T) {
+	const csvType, jsonlType = "text/csv", "application/x-ndjson"
+	for _, tt := range []struct {
+		name        string
+		query       string
+		contentType string
+		body        string
+		failOn      string // usuario cuyo alta falla en la transacción
+		status      int
+		actions     string            // acción de cada fila
+		errors      map[string]string // "línea campo" → código
+		committed   bool
+		stored      string // usuarios guardados al final
+	}{
+		{"CSV", "", csvType, "username,password,zona\nana,secreto123,norte\nbea,secreto123,sur\n", "",
+			http.StatusOK, "created,created", nil, true, "ana,bea"},
+		{"simulación", "?dry_run=true", csvType, "username,password,zona\nana,secreto123,norte\nbea,secreto123,sur\n", "",
+			http.StatusOK, "created,created", nil, false, ""},
+		{"atómico con una fila inválida", "?atomic=true", csvType, "username,password,zona\nana,secreto123,norte\nbea,123,sur\ncarla,secreto123,norte\n", "",
+			http.StatusUnprocessableEntity, "skipped,invalid,skipped", map[string]string{"3 password": problem.FieldTooShort}, false, ""},
+		{"atómico válido", "?atomic=true", csvType, "username,password,zona\nana,secreto123,norte\nbea,secreto123,sur\n", "",
+			http.StatusOK, "created,created", nil, true, "ana,bea"},
+		{"no atómico con una fila inválida", "", csvType, "username,password,zona\nana,secreto123,norte\nbea,123,sur\ncarla,secreto123,norte\n", "",
+			http.StatusOK, "created,invalid,created", map[string]string{"3 password": problem.FieldTooShort}, true, "ana,carla"},
+		{"CSV con columnas de menos", "", csvType, "username,password,zona\nana,secreto123,norte\nbea,sur\n", "",
+			http.StatusOK, "created,invalid", map[string]string{"3 columns": problem.FieldInvalidValue}, true, "ana"},
+		{"JSONL con una línea rota", "", jsonlType, `{"username":"ana","password":"secreto123","zona":"norte"}` + "\n{roto\n" + `{"username":"bea","password":"secreto123","zona":"sur"}`, "",
+			http.StatusOK, "created,invalid,created", map[string]string{"2 line": problem.FieldInvalidJSON}, true, "ana,bea"},
+		{"usuario repetido en el archivo", "", csvType, "username,password,zona\nana,secreto123,norte\nana,secreto123,sur\n", "",
+			http.StatusOK, "created,invalid", map[string]string{"3 username": problem.FieldDuplicate}, true, "ana"},
+		{"zona desconocida", "", csvType, "username,password,zona\nana,secreto123,este\n", "",
+			http.StatusOK, "invalid", map[string]string{"2 zona": problem.FieldUnknownZona}, true, ""},
+		{"falla un grupo", "?chunk=2", csvType, "username,password,zona\nana,secreto123,norte\nbea,secreto123,sur\ncarla,secreto123,norte\ndora,secreto123,sur\n", "carla",
+			http.StatusOK, "created,created,failed,failed", map[string]string{"4 username": problem.FieldConflict}, true, "ana,bea"},
+		{"falla el único grupo atómico", "?atomic=true", csvType, "username,password,zona\nana,secreto123,norte\nbea,secreto123,sur\n", "bea",
+			http.StatusUnprocessableEntity, "failed,failed", map[string]string{"3 username": problem.FieldConflict}, false, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t)
+			if tt.failOn != "" {
+				h.users = failingUsers{h.users, tt.failOn}
+			}
+			status, report := postImport(t, h, tt.query, tt.contentType, tt.body)
+			if status != tt.status {
+				t.Fatalf("estado %d, se esperaba %d: %+v", status, tt.status, report)
+			}
+
+			var actions []string
+			errs := map[string]string{}
+			for _, row := range report.Rows {
+				actions = append(actions, row.Action)
+				for _, fe := range row.Errors {
+					errs[strconv.Itoa(row.Line)+" "+fe.Field] = fe.Code
+				}
+			}
+			if got := strings.Join(actions, ","); got != tt.actions {
+				t.Errorf("acciones %s, se esperaba %s", got, tt.actions)
+			}
+			if !equalCodes(errs, tt.errors) {
+				t.Errorf("errores %v, se esperaba %v", errs, tt.errors)
+			}
+			if report.Committed != tt.committed {
+				t.Errorf("committed = %v", report.Committed)
+			}
+
+			page, err := h.users.List(context.Background(), repository.ListQuery{Limit: 100})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var stored []string
+			for _, u := range page.Users {
+				stored = append(stored, u.Username)
+			}
+			if got := strings.Join(stored, ","); got != tt.stored {
+				t.Errorf("guardados %q, se esperaba %q", got, tt.stored)
+			}
+		})
+	}
+}
+
+// Sin contraseña se genera una (se devuelve una sola vez) o, con
+// passwords=invite, una invitación que activa la cuenta
+func TestImportWithoutPassword(t *testing.T) {
+	h := newTestHandler(t)
+	login := func(username, password string) int {
+		return serve(h.Login, request{method: "POST", target: "/api/v1/auth/login", body: `{"username":"` + username + `","password":"` + password + `"}`}).Code
+	}
+
+	_, report := postImport(t, h, "", "text/csv", "username,zona\nana,norte\n")
+	row := report.Rows[0]
+	if row.Action != importCreated || row.Password == "" || row.Invitation != "" {
+		t.Fatalf("fila %+v", row)
+	}
+	if code := login("ana", row.Password); code != http.StatusOK {
+		t.Errorf("entrar con la contraseña generada: %d", code)
+	}
+
+	_, report = postImport(t, h, "?passwords=invite", "text/csv", "username,zona\nbea,sur\n")
+	row = report.Rows[0]
+	if row.Action != importCreated || row.Password != "" || row.Invitation == "" {
+		t.Fatalf("fila %+v", row)
+	}
+	bea, err := h.users.GetByUsername(context.Background(), "bea")
+	if err != nil || bea.Status != models.StatusInactive {
+		t.Fatalf("invitada: %+v %v", bea, err)
+	}
+
+	accept := request{method: "POST", target: "/api/v1/auth/invitations/accept", body: `{"token":"` + row.Invitation + `","password":"secreto123"}`}
+	if rec := serve(h.AcceptInvitation, accept); rec.Code != http.StatusOK {
+		t.Fatalf("aceptar: %d %s", rec.Code, rec.Body)
+	}
+	if bea, _ = h.users.GetByUsername(context.Background(), "bea"); bea.Status != models.StatusActive {
+		t.Errorf("estado %q tras aceptar", bea.Status)
+	}
+	if code := login("bea", "secreto123"); code != http.StatusOK {
+		t.Errorf("entrar tras aceptar: %d", code)
+	}
+	// La invitación solo sirve una vez
+	if rec := serve(h.AcceptInvitation, accept); rec.Code != http.StatusGone {
+		t.Errorf("aceptar de nuevo: %d", rec.Code)
+	}
+}
@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"api3/src/problem"
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+)
+
+// Datos de entrada de cada operación. Las reglas van en las etiquetas
+// validate (ver validation.go) y se decodifican igual desde JSON, multipart
+// o application/x-www-form-urlencoded.
+
+// RegisterInput son los datos para crear un usuario
+type RegisterInput struct {
+	Username    string `json:"username" validate:"required,min=3,max=50,username"`
+	DisplayName string `json:"displayName" validate:"max=100"`
+	Password    string `json:"password" validate:"required,min=6,max=72"`
+	Role        string `json:"role" validate:"omitempty,role"`
+	Zona        string `json:"zona" validate:"required,max=100,zona"`
+	imageInput
+}
+
+// LoginInput son las credenciales; no se aplican reglas de longitud para
+// que las contraseñas anteriores sigan sirviendo
+type LoginInput struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// LoginResponse es la respuesta de un inicio de sesión correcto
+type LoginResponse struct {
+	Token     string `json:"token"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	Zona      string `json:"zona"`
+	Image     string `json:"image"`
+	ImageType string `json:"imageType"`
+	ImageURL  string `json:"imageUrl"`
+}
+
+// ReplaceUserInput es el usuario completo de PUT. Password es opcional
+// porque no forma parte de la representación.
+type ReplaceUserInput struct {
+	Username    string  `json:"username" validate:"required,min=3,max=50,username"`
+	DisplayName string  `json:"displayName" validate:"max=100"`
+	Password    *string `json:"password" validate:"omitnil,min=6,max=72"`
+	Role        string  `json:"role" validate:"required,role"`
+	Zona        string  `json:"zona" validate:"required,max=100,zona"`
+	Status      string  `json:"status" validate:"omitempty,status"`
+	imageInput
+}
+
+// LegacyUpdateInput es el cuerpo de PUT /update/{id}: un campo vacío
+// significa "sin cambios"
+type LegacyUpdateInput struct {
+	Username string `json:"username" validate:"omitempty,min=3,max=50,username"`
+	Password string `json:"password" validate:"omitempty,min=6,max=72"`
+	Role     string `json:"role" validate:"omitempty,role"`
+	Zona     string `json:"zona" validate:"omitempty,max=100,zona"`
+	Status   string `json:"status" validate:"omitempty,status"`
+	imageInput
+}
+
+// ImportRowInput es una fila de POST /users/import. Sin password se genera
+// una o se emite una invitación.
+type ImportRowInput struct {
+	Username    string `json:"username" validate:"required,min=3,max=50,username"`
+	DisplayName string `json:"displayName" validate:"max=100"`
+	Password    string `json:"password" validate:"omitempty,min=6,max=72"`
+	Role        string `json:"role" validate:"omitempty,role"`
+	Zona        string `json:"zona" validate:"required,max=100,zona"`
+	Status      string `json:"status" validate:"omitempty,status"`
+}
+
+// AcceptInvitationInput canjea una invitación por una contraseña
+type AcceptInvitationInput struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6,max=72"`
+}
+
+// ZoneInput son los datos para crear una zona
+type ZoneInput struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// imageInput es la imagen de perfil: en base64 desde JSON o formularios, o
+// como archivo desde multipart
+type imageInput struct {
+	Image string `json:"image" validate:"omitempty,base64"`
+	file  []byte
+}
+
+func (in *imageInput) setImageFile(data []byte) { in.file = data }
+
+// imageBytes devuelve la imagen enviada (nil si no hay); se llama después
+// de validar, así que el base64 ya es correcto
+func (in *imageInput) imageBytes() []byte {
+	if in.file != nil {
+		return in.file
+	}
+	if in.Image == "" {
+		return nil
+	}
+	data, _ := base64.StdEncoding.DecodeString(in.Image)
+	return data
+}
+
+// decodeInput llena dst con el cuerpo de la petición según su Content-Type
+func decodeInput(r *http.Request, dst interface{}) *problem.Problem {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			return problem.New(problem.CodeInvalidBody).WithDetail("detail.malformed_form", err.Error())
+		}
+		if err := decodeForm(r.MultipartForm.Value, dst); err != nil {
+			return problem.New(problem.CodeInvalidBody).WithDetail("detail.malformed_form", err.Error())
+		}
+		receiver, ok := dst.(interface{ setImageFile([]byte) })
+		if !ok {
+			return nil
+		}
+		file, _, err := r.FormFile("image")
+		if err == http.ErrMissingFile {
+			return nil
+		}
+		if err != nil {
+			return problem.New(problem.CodeInvalidBody).WithDetail("detail.malformed_form", err.Error())
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return problem.Internal(err)
+		}
+		receiver.setImageFile(data)
+		return nil
+
+	case "application/x-www-form-urlencoded":
+		// Clientes como curl -d envían JSON con este tipo; antes se aceptaba
+		if looksLikeJSON(r) {
+			return decodeJSON(r, dst)
+		}
+		if err := r.ParseForm(); err != nil {
+			return problem.New(problem.CodeInvalidBody).WithDetail("detail.malformed_form", err.Error())
+		}
+		if err := decodeForm(r.PostForm, dst); err != nil {
+			return problem.New(problem.CodeInvalidBody).WithDetail("detail.malformed_form", err.Error())
+		}
+		return nil
+
+	default:
+		return decodeJSON(r, dst)
+	}
+}
+
+func decodeJSON(r *http.Request, dst interface{}) *problem.Problem {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		return problem.New(problem.CodeInvalidBody).WithDetail("detail.malformed_json", err.Error())
+	}
+	return nil
+}
+
+// Mira el primer carácter del cuerpo sin consumirlo
+func looksLikeJSON(r *http.Request) bool {
+	body := bufio.NewReader(r.Body)
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{body, r.Body}
+	for {
+		b, err := body.Peek(1)
+		if err != nil {
+			return false
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			body.ReadByte()
+		default:
+			return b[0] == '{'
+		}
+	}
+}
+
+// Los formularios se pasan por JSON para reutilizar las etiquetas json de
+// los DTO; todos sus campos son texto
+func decodeForm(values url.Values, dst interface{}) error {
+	fields := map[string]string{}
+	for name, v := range values {
+		if len(v) > 0 {
+			fields[name] = v[0]
+		}
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"api3/src/events"
+	"api3/src/models"
+	"api3/src/problem"
+	"api3/src/repository"
+	"api3/src/utils"
+	"errors"
+	"net/http"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AcceptInvitation canjea una invitación por una contraseña y activa al
+// usuario
+func (h *UserHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var input AcceptInvitationInput
+	if prob := decodeInput(r, &input); prob != nil {
+		problem.Write(w, r, prob)
+		return
+	}
+	if prob := h.validateInput(r.Context(), &input, nil); prob != nil {
+		problem.Write(w, r, prob)
+		return
+	}
+
+	claims, err := utils.ValidateInvitationToken(input.Token)
+	if err != nil {
+		problem.Write(w, r, problem.New(problem.CodeInvitationInvalid))
+		return
+	}
+	user, err := h.users.GetByID(r.Context(), int(claims.UserID))
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && user.Version != claims.Version) {
+		problem.Write(w, r, problem.New(problem.CodeInvitationInvalid))
+		return
+	}
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	hashedPwd, _ := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	user.Password = string(hashedPwd)
+	user.Status = models.StatusActive
+	if err := h.users.Update(r.Context(), user); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			problem.Write(w, r, problem.New(problem.CodeInvitationInvalid))
+			return
+		}
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+	h.publish(r.Context(), events.UserUpdated, user, "")
+	w.Header().Set("ETag", user.ETag())
+	writeMessage(w, r, http.StatusOK, "message.invitation_accepted")
+}
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"api3/src/models"
+	"api3/src/problem"
+	"api3/src/repository"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// UserListResponse es el sobre de GET /users
+type UserListResponse struct {
+	Data   []models.User `json:"data"`
+	Total  int64         `json:"total"`
+	Limit  int           `json:"limit"`
+	Offset *int          `json:"offset,omitempty"`
+	Next   *string       `json:"next"`
+	Prev   *string       `json:"prev"`
+}
+
+// Lee la paginación, los filtros y el orden de la URL
+func parseListQuery(r *http.Request) (repository.ListQuery, *problem.Problem) {
+	return parseListParams(r.URL.Query())
+}
+
+// parseListParams hace lo mismo con los parámetros ya separados (GraphQL
+// los convierte a este formato para aplicar las mismas reglas)
+func parseListParams(params url.Values) (repository.ListQuery, *problem.Problem) {
+	q := repository.ListQuery{Limit: defaultPageSize}
+	var prob *problem.Problem
+	if q.Filter, q.Sort, prob = parseFilterSort(params); prob != nil {
+		return q, prob
+	}
+
+	if raw := params.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return q, invalidParam("limit", problem.FieldOutOfRange).WithDetail("detail.limit_range", maxPageSize)
+		}
+		q.Limit = limit
+	}
+	if raw := params.Get("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return q, invalidParam("offset", problem.FieldInvalidValue)
+		}
+		q.Offset = offset
+	}
+	if raw := params.Get("cursor"); raw != "" {
+		if params.Has("offset") {
+			return q, problem.New(problem.CodeInvalidQuery).WithDetail("detail.cursor_and_offset")
+		}
+		cursor, err := repository.DecodeCursor(raw)
+		if err != nil {
+			return q, invalidParam("cursor", problem.FieldInvalidValue)
+		}
+		q.Cursor = cursor
+	}
+	return q, nil
+}
+
+// Lee los filtros y el orden, comunes al listado y a la exportación
+func parseFilterSort(params url.Values) (repository.UserFilter, []repository.SortField, *problem.Problem) {
+	filter := repository.UserFilter{
+		Role:           params.Get("role"),
+		Zona:           params.Get("zona"),
+		Status:         params.Get("status"),
+		UsernamePrefix: params.Get("username"),
+	}
+	if filter.Status != "" && !models.ValidStatus(filter.Status) {
+		return filter, nil, invalidParam("status", problem.FieldInvalidStatus)
+	}
+
+	sort, err := repository.ParseSort(params.Get("sort"))
+	if err != nil {
+		return filter, nil, invalidParam("sort", problem.FieldInvalidValue).WithDetail("detail.sortable", strings.Join(repository.SortableColumns(), ", "))
+	}
+	return filter, sort, nil
+}
+
+func invalidParam(param, code string) *problem.Problem {
+	return problem.New(problem.CodeInvalidQuery).WithFields(map[string]string{param: code})
+}
+
+// Arma el sobre con los enlaces next/prev. Si el cliente pidió offset los
+// enlaces siguen por offset; si no, por cursor.
+func newUserListResponse(r *http.Request, q repository.ListQuery, page *repository.UserPage) UserListResponse {
+	resp := UserListResponse{Data: page.Users, Total: page.Total, Limit: q.Limit}
+	if resp.Data == nil {
+		resp.Data = []models.User{}
+	}
+
+	if r.URL.Query().Has("offset") {
+		offset := q.Offset
+		resp.Offset = &offset
+		if offset+q.Limit < int(page.Total) {
+			resp.Next = pageLink(r, "offset", strconv.Itoa(offset+q.Limit))
+		}
+		if offset > 0 {
+			prev := offset - q.Limit
+			if prev < 0 {
+				prev = 0
+			}
+			resp.Prev = pageLink(r, "offset", strconv.Itoa(prev))
+		}
+		return resp
+	}
+
+	if page.Next != nil {
+		resp.Next = pageLink(r, "cursor", page.Next.Encode())
+	}
+	if page.Prev != nil {
+		resp.Prev = pageLink(r, "cursor", page.Prev.Encode())
+	}
+	return resp
+}
+
+// Copia la URL actual cambiando solo la posición
+func pageLink(r *http.Request, key, value string) *string {
+	params := r.URL.Query()
+	params.Del("cursor")
+	params.Del("offset")
+	params.Set(key, value)
+	link := (&url.URL{Path: r.URL.Path, RawQuery: params.Encode()}).String()
+	return &link
+}
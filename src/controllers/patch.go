@@ -0,0 +1,359 @@
+package controllers
+
+import (
+	"api3/src/models"
+	"api3/src/problem"
+	"api3/src/repository"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Tipos de contenido aceptados por PATCH
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// Campos que se pueden escribir; los de solo lectura (id, imageUrl,
+// imageType, version, updatedAt) se ignoran para que un cliente pueda
+// reenviar lo que leyó. La versión se comprueba con If-Match, no en el cuerpo.
+var (
+	writableFields = map[string]bool{"username": true, "displayName": true, "password": true, "role": true, "zona": true, "status": true, "image": true}
+	readOnlyFields = map[string]bool{"id": true, "imageUrl": true, "imageType": true, "version": true, "updatedAt": true}
+)
+
+// UserPatch son los cambios a aplicar a un usuario (el DTO de PATCH). Un
+// puntero nil deja el campo como está; ImageSet con Image nil quita la imagen.
+type UserPatch struct {
+	Username    *string `json:"username" validate:"omitnil,min=3,max=50,username"`
+	DisplayName *string `json:"displayName" validate:"omitnil,max=100"`
+	Password    *string `json:"password" validate:"omitnil,min=6,max=72"`
+	Role        *string `json:"role" validate:"omitnil,role"`
+	Zona        *string `json:"zona" validate:"omitnil,min=1,max=100,zona"`
+	Status      *string `json:"status" validate:"omitnil,status"`
+	Image       []byte  `json:"-"`
+	ImageSet    bool    `json:"-"`
+}
+
+func (p UserPatch) empty() bool {
+	return p.Username == nil && p.DisplayName == nil && p.Password == nil && p.Role == nil && p.Zona == nil && p.Status == nil && !p.ImageSet
+}
+
+// fieldErrors asocia cada campo rechazado con un código problem.Field*
+type fieldErrors map[string]string
+
+// PatchUser aplica un JSON Merge Patch (RFC 7386) o un JSON Patch (RFC 6902)
+func (h *UserHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.userForUpdate(w, r)
+	if !ok {
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var fields map[string]json.RawMessage
+	switch mediaType {
+	case mergePatchType, "application/json":
+		if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+			problem.Write(w, r, problem.New(problem.CodeInvalidBody).WithDetail("detail.malformed_json", err.Error()))
+			return
+		}
+	case jsonPatchType:
+		var ops []patchOperation
+		if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+			problem.Write(w, r, problem.New(problem.CodeInvalidPatch).WithDetail("detail.malformed_json", err.Error()))
+			return
+		}
+		var prob *problem.Problem
+		if fields, prob = applyJSONPatch(patchDocument(user), ops); prob != nil {
+			problem.Write(w, r, prob)
+			return
+		}
+	default:
+		w.Header().Set("Accept-Patch", mergePatchType+", "+jsonPatchType)
+		problem.Write(w, r, problem.New(problem.CodeUnsupportedMedia).WithDetail("detail.patch_types", mergePatchType, jsonPatchType))
+		return
+	}
+
+	patch, errs := patchFromFields(fields)
+	if prob := h.validateInput(r.Context(), &patch, errs); prob != nil {
+		problem.Write(w, r, prob)
+		return
+	}
+	if h.saveChanges(w, r, user, patch) {
+		h.writeUpdated(w, r, user)
+	}
+}
+
+// ReplaceUser reemplaza el usuario completo
+func (h *UserHandler) ReplaceUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.userForUpdate(w, r)
+	if !ok {
+		return
+	}
+
+	var input ReplaceUserInput
+	if prob := decodeInput(r, &input); prob != nil {
+		problem.Write(w, r, prob)
+		return
+	}
+	if prob := h.validateInput(r.Context(), &input, nil); prob != nil {
+		problem.Write(w, r, prob)
+		return
+	}
+	if input.Status == "" {
+		input.Status = models.StatusActive
+	}
+
+	// Sin imagen se quita la que tuviera
+	patch := UserPatch{
+		Username:    &input.Username,
+		DisplayName: &input.DisplayName,
+		Password:    input.Password,
+		Role:        &input.Role,
+		Zona:        &input.Zona,
+		Status:      &input.Status,
+		Image:       input.imageBytes(),
+		ImageSet:    true,
+	}
+	if h.saveChanges(w, r, user, patch) {
+		h.writeUpdated(w, r, user)
+	}
+}
+
+// Busca el usuario de la ruta y comprueba If-Match; responde 400/404/412 si
+// no se puede modificar
+func (h *UserHandler) userForUpdate(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, r, problem.New(problem.CodeInvalidID))
+		return nil, false
+	}
+	user, err := h.users.GetByID(r.Context(), id)
+	if err != nil {
+		problem.Write(w, r, lookupProblem(err))
+		return nil, false
+	}
+	if !ifMatch(r, user) {
+		h.writePreconditionFailed(w, r, user)
+		return nil, false
+	}
+	return user, true
+}
+
+func (h *UserHandler) writeUpdated(w http.ResponseWriter, r *http.Request, user *models.User) {
+	h.formatImage(r, user)
+	w.Header().Set("ETag", user.ETag())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// Guarda los cambios ya validados; si algo falla escribe la respuesta de
+// error y devuelve false
+func (h *UserHandler) saveChanges(w http.ResponseWriter, r *http.Request, user *models.User, p UserPatch) bool {
+	err := h.updateUser(r.Context(), user, p)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		h.writeVersionConflict(w, r, user.ID)
+		return false
+	}
+	if err != nil {
+		problem.Write(w, r, asProblem(err))
+		return false
+	}
+	return true
+}
+
+// Convierte los campos de un documento JSON en cambios. null solo es válido
+// para image (quita la imagen); los campos de texto son obligatorios.
+func patchFromFields(fields map[string]json.RawMessage) (UserPatch, fieldErrors) {
+	var p UserPatch
+	errs := fieldErrors{}
+	for name, raw := range fields {
+		if readOnlyFields[name] {
+			continue
+		}
+		if !writableFields[name] {
+			errs[name] = problem.FieldUnknown
+			continue
+		}
+
+		isNull := bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+		if name == "image" {
+			p.ImageSet = true
+			if isNull {
+				continue
+			}
+			var encoded string
+			if err := json.Unmarshal(raw, &encoded); err != nil {
+				errs[name] = problem.FieldInvalidBase64
+				continue
+			}
+			data, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil || len(data) == 0 {
+				errs[name] = problem.FieldInvalidBase64
+				continue
+			}
+			p.Image = data
+			continue
+		}
+
+		if isNull && name == "displayName" {
+			// Quitar el nombre para mostrar lo deja vacío
+			p.DisplayName = new(string)
+			continue
+		}
+		if isNull {
+			errs[name] = problem.FieldNull
+			continue
+		}
+		value := new(string)
+		if err := json.Unmarshal(raw, value); err != nil {
+			errs[name] = problem.FieldNotString
+			continue
+		}
+		switch name {
+		case "username":
+			p.Username = value
+		case "displayName":
+			p.DisplayName = value
+		case "password":
+			p.Password = value
+		case "role":
+			p.Role = value
+		case "zona":
+			p.Zona = value
+		case "status":
+			p.Status = value
+		}
+	}
+	return p, errs
+}
+
+// patchOperation es una operación de JSON Patch (RFC 6902)
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Documento sobre el que se aplica un JSON Patch: la representación del
+// usuario, con la imagen como su URL (o null). La contraseña no aparece,
+// pero se puede añadir.
+func patchDocument(user *models.User) map[string]interface{} {
+	doc := map[string]interface{}{
+		"username":    user.Username,
+		"displayName": user.DisplayName,
+		"role":        user.Role,
+		"zona":        user.Zona,
+		"status":      user.Status,
+		"image":       nil,
+	}
+	if user.ImageKey != "" {
+		withURL := *user
+		withURL.FormatImageURL()
+		doc["image"] = withURL.ImageURL
+	}
+	return doc
+}
+
+// Aplica las operaciones sobre doc y devuelve solo los campos que cambiaron;
+// un campo eliminado queda como null. Un parche mal formado es invalid_patch;
+// uno que no encaja con el estado actual (falla un test o falta el campo),
+// patch_conflict.
+func applyJSONPatch(doc map[string]interface{}, ops []patchOperation) (map[string]json.RawMessage, *problem.Problem) {
+	result := make(map[string]interface{}, len(doc))
+	for k, v := range doc {
+		result[k] = v
+	}
+
+	invalid := func(key string, args ...interface{}) *problem.Problem {
+		return problem.New(problem.CodeInvalidPatch).WithDetail(key, args...)
+	}
+	conflict := func(key string, args ...interface{}) *problem.Problem {
+		return problem.New(problem.CodePatchConflict).WithDetail(key, args...)
+	}
+
+	for i, op := range ops {
+		path, ok := patchField(op.Path)
+		if !ok {
+			return nil, invalid("detail.patch_bad_path", i, op.Path)
+		}
+		var value interface{}
+		if op.Op == "add" || op.Op == "replace" || op.Op == "test" {
+			if op.Value == nil {
+				return nil, invalid("detail.patch_missing_value", i)
+			}
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				return nil, invalid("detail.patch_invalid_value", i)
+			}
+		}
+
+		current, exists := result[path]
+		switch op.Op {
+		case "add":
+			result[path] = value
+		case "replace":
+			if !exists {
+				return nil, conflict("detail.patch_missing_path", i, op.Path)
+			}
+			result[path] = value
+		case "remove":
+			if !exists {
+				return nil, conflict("detail.patch_missing_path", i, op.Path)
+			}
+			delete(result, path)
+		case "test":
+			if !exists || !reflect.DeepEqual(current, value) {
+				return nil, conflict("detail.patch_test_failed", i, op.Path)
+			}
+		case "move", "copy":
+			from, ok := patchField(op.From)
+			if !ok {
+				return nil, invalid("detail.patch_bad_path", i, op.From)
+			}
+			v, ok := result[from]
+			if !ok {
+				return nil, conflict("detail.patch_missing_path", i, op.From)
+			}
+			if op.Op == "move" {
+				delete(result, from)
+			}
+			result[path] = v
+		default:
+			return nil, invalid("detail.patch_unknown_op", i, op.Op)
+		}
+	}
+
+	changes := map[string]json.RawMessage{}
+	for k, before := range doc {
+		after, ok := result[k]
+		if !ok {
+			changes[k] = json.RawMessage("null")
+		} else if !reflect.DeepEqual(before, after) {
+			changes[k], _ = json.Marshal(after)
+		}
+	}
+	for k, after := range result {
+		if _, ok := doc[k]; !ok {
+			changes[k], _ = json.Marshal(after)
+		}
+	}
+	return changes, nil
+}
+
+// Los usuarios son un objeto plano: el puntero JSON solo puede tener un nivel
+func patchField(pointer string) (string, bool) {
+	if !strings.HasPrefix(pointer, "/") || strings.Count(pointer, "/") != 1 || len(pointer) == 1 {
+		return "", false
+	}
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(pointer[1:]), true
+}
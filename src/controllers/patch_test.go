@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"api3/src/problem"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// Documento de un usuario sin imagen, como lo genera patchDocument
+func testPatchDocument() map[string]interface{} {
+	return map[string]interface{}{
+		"username":    "ana",
+		"displayName": "Ana",
+		"role":        "user",
+		"zona":        "norte",
+		"status":      "active",
+		"image":       nil,
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		ops     string
+		changes map[string]string // campo → JSON del nuevo valor
+		code    string
+	}{
+		{"add", `[{"op":"add","path":"/password","value":"secreto123"}]`, map[string]string{"password": `"secreto123"`}, ""},
+		{"add sobre un campo existente", `[{"op":"add","path":"/zona","value":"sur"}]`, map[string]string{"zona": `"sur"`}, ""},
+		{"remove", `[{"op":"remove","path":"/displayName"}]`, map[string]string{"displayName": "null"}, ""},
+		{"replace", `[{"op":"replace","path":"/role","value":"admin"}]`, map[string]string{"role": `"admin"`}, ""},
+		{"move", `[{"op":"move","from":"/displayName","path":"/username"}]`, map[string]string{"username": `"Ana"`, "displayName": "null"}, ""},
+		{"copy", `[{"op":"copy","from":"/zona","path":"/displayName"}]`, map[string]string{"displayName": `"norte"`}, ""},
+		{"test y replace", `[{"op":"test","path":"/status","value":"active"},{"op":"replace","path":"/status","value":"blocked"}]`, map[string]string{"status": `"blocked"`}, ""},
+		{"test de null", `[{"op":"test","path":"/image","value":null}]`, map[string]string{}, ""},
+		{"sin cambios", `[{"op":"replace","path":"/zona","value":"norte"}]`, map[string]string{}, ""},
+		{"test fallido", `[{"op":"test","path":"/role","value":"admin"},{"op":"replace","path":"/role","value":"user"}]`, nil, problem.CodePatchConflict},
+		{"test de un campo ausente", `[{"op":"test","path":"/password","value":"x"}]`, nil, problem.CodePatchConflict},
+		{"replace de un campo ausente", `[{"op":"replace","path":"/password","value":"x"}]`, nil, problem.CodePatchConflict},
+		{"remove de un campo ausente", `[{"op":"remove","path":"/password"}]`, nil, problem.CodePatchConflict},
+		{"move desde un campo ausente", `[{"op":"move","from":"/password","path":"/zona"}]`, nil, problem.CodePatchConflict},
+		{"puntero anidado", `[{"op":"replace","path":"/a/b","value":"x"}]`, nil, problem.CodeInvalidPatch},
+		{"from anidado", `[{"op":"copy","from":"/a/b","path":"/zona"}]`, nil, problem.CodeInvalidPatch},
+		{"puntero vacío", `[{"op":"replace","path":"","value":"x"}]`, nil, problem.CodeInvalidPatch},
+		{"sin value", `[{"op":"add","path":"/zona"}]`, nil, problem.CodeInvalidPatch},
+		{"operación desconocida", `[{"op":"merge","path":"/zona","value":"sur"}]`, nil, problem.CodeInvalidPatch},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var ops []patchOperation
+			if err := json.Unmarshal([]byte(tt.ops), &ops); err != nil {
+				t.Fatal(err)
+			}
+			changes, prob := applyJSONPatch(testPatchDocument(), ops)
+			if tt.code != "" {
+				if prob == nil || prob.Code != tt.code {
+					t.Fatalf("problema %+v, se esperaba %s", prob, tt.code)
+				}
+				return
+			}
+			if prob != nil {
+				t.Fatalf("problema %+v", prob)
+			}
+			got := map[string]string{}
+			for k, v := range changes {
+				got[k] = string(v)
+			}
+			if !reflect.DeepEqual(got, tt.changes) {
+				t.Errorf("cambios %v, se esperaba %v", got, tt.changes)
+			}
+		})
+	}
+}
+
+// Quitar un campo obligatorio llega a patchFromFields como null
+func TestJSONPatchRemoveRequired(t *testing.T) {
+	changes, prob := applyJSONPatch(testPatchDocument(), []patchOperation{{Op: "remove", Path: "/username"}})
+	if prob != nil {
+		t.Fatal(prob)
+	}
+	if _, errs := patchFromFields(changes); errs["username"] != problem.FieldNull {
+		t.Errorf("errores %v, se esperaba username: %s", errs, problem.FieldNull)
+	}
+}
+
+func TestPatchField(t *testing.T) {
+	for _, tt := range []struct {
+		pointer string
+		field   string
+		ok      bool
+	}{
+		{"/zona", "zona", true},
+		{"/a~1b", "a/b", true},
+		{"/a~0b", "a~b", true},
+		{"/~01", "~1", true}, // ~0 se decodifica sin volver a leer el resultado
+		{"/a/b", "", false},
+		{"/", "", false},
+		{"zona", "", false},
+		{"", "", false},
+	} {
+		field, ok := patchField(tt.pointer)
+		if field != tt.field || ok != tt.ok {
+			t.Errorf("patchField(%q) = %q, %v; se esperaba %q, %v", tt.pointer, field, ok, tt.field, tt.ok)
+		}
+	}
+}
+
+func TestPatchFromFields(t *testing.T) {
+	str := func(s string) *string { return &s }
+	for _, tt := range []struct {
+		name   string
+		fields string
+		patch  UserPatch
+		errs   fieldErrors
+	}{
+		{"texto", `{"username":"bea","role":"admin","zona":"sur","status":"blocked","password":"secreto123"}`,
+			UserPatch{Username: str("bea"), Role: str("admin"), Zona: str("sur"), Status: str("blocked"), Password: str("secreto123")}, fieldErrors{}},
+		{"displayName null lo vacía", `{"displayName":null}`, UserPatch{DisplayName: str("")}, fieldErrors{}},
+		{"image null la quita", `{"image":null}`, UserPatch{ImageSet: true}, fieldErrors{}},
+		{"image en base64", `{"image":"aG9sYQ=="}`, UserPatch{Image: []byte("hola"), ImageSet: true}, fieldErrors{}},
+		{"image no es texto", `{"image":42}`, UserPatch{ImageSet: true}, fieldErrors{"image": problem.FieldInvalidBase64}},
+		{"image mal codificada", `{"image":"%%%"}`, UserPatch{ImageSet: true}, fieldErrors{"image": problem.FieldInvalidBase64}},
+		{"image vacía", `{"image":""}`, UserPatch{ImageSet: true}, fieldErrors{"image": problem.FieldInvalidBase64}},
+		{"null en un campo obligatorio", `{"username":null,"zona":null}`, UserPatch{}, fieldErrors{"username": problem.FieldNull, "zona": problem.FieldNull}},
+		{"no es texto", `{"role":1,"status":true}`, UserPatch{}, fieldErrors{"role": problem.FieldNotString, "status": problem.FieldNotString}},
+		{"representación completa de un GET", `{"id":1,"username":"ana","displayName":"Ana","role":"user","zona":"norte","status":"active","imageUrl":"/api/v1/users/1/image?v=abc","imageType":"image/png","version":3,"updatedAt":"2026-10-19T10:00:00Z"}`,
+			UserPatch{Username: str("ana"), DisplayName: str("Ana"), Role: str("user"), Zona: str("norte"), Status: str("active")}, fieldErrors{}},
+		{"solo lectura y desconocidos", `{"id":7,"imageUrl":"/x","imageType":"image/png","edad":30}`, UserPatch{}, fieldErrors{"edad": problem.FieldUnknown}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.fields), &fields); err != nil {
+				t.Fatal(err)
+			}
+			patch, errs := patchFromFields(fields)
+			if !reflect.DeepEqual(patch, tt.patch) {
+				t.Errorf("cambios %+v, se esperaba %+v", patch, tt.patch)
+			}
+			if !reflect.DeepEqual(errs, tt.errs) {
+				t.Errorf("errores %v, se esperaba %v", errs, tt.errs)
+			}
+		})
+	}
+}
+
+// Un cliente puede reenviar tal cual lo que leyó con GET
+func TestPatchEchoesGet(t *testing.T) {
+	h := newTestHandler(t)
+	id := register(t, h, "ana", "norte")
+	target := "/api/v1/users/" + strconv.Itoa(id)
+
+	got := serve(h.GetUser, request{method: "GET", target: target, vars: userVars(id)})
+	if got.Code != http.StatusOK {
+		t.Fatalf("GET: %d %s", got.Code, got.Body)
+	}
+	rec := serve(h.PatchUser, request{method: "PATCH", target: target, vars: userVars(id), body: got.Body.String(), headers: []string{"Content-Type", mergePatchType}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("estado %d: %s", rec.Code, rec.Body)
+	}
+	var user map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &user); err != nil {
+		t.Fatal(err)
+	}
+	if user["username"] != "ana" || user["zona"] != "norte" {
+		t.Errorf("usuario %v", user)
+	}
+}
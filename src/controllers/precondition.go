@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"api3/src/models"
+	"api3/src/problem"
+	"net/http"
+	"strings"
+)
+
+// ifMatch indica si la versión del usuario cumple la cabecera If-Match. Sin
+// cabecera se acepta; la comparación es fuerte, así que un ETag débil (W/)
+// nunca coincide.
+func ifMatch(r *http.Request, user *models.User) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+	return etagListContains(header, user.ETag(), false)
+}
+
+// ifNoneMatch indica si el cliente ya tiene esta versión (para responder 304)
+func ifNoneMatch(r *http.Request, user *models.User) bool {
+	header := r.Header.Get("If-None-Match")
+	return header != "" && etagListContains(header, user.ETag(), true)
+}
+
+func etagListContains(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Responde 412 con el estado actual del usuario (en "current") para que el
+// cliente pueda rehacer su cambio
+func (h *UserHandler) writePreconditionFailed(w http.ResponseWriter, r *http.Request, user *models.User) {
+	h.formatImage(r, user)
+	w.Header().Set("ETag", user.ETag())
+	problem.Write(w, r, problem.New(problem.CodePreconditionFailed).WithCurrent(user))
+}
+
+// Un guardado chocó con otro cambio entre la lectura y la escritura. Si el
+// cliente envió If-Match es un 412 con el estado nuevo; si no, un 409 para
+// que reintente.
+func (h *UserHandler) writeVersionConflict(w http.ResponseWriter, r *http.Request, id int) {
+	current, err := h.users.GetByID(r.Context(), id)
+	if err != nil {
+		problem.Write(w, r, lookupProblem(err))
+		return
+	}
+	if r.Header.Get("If-Match") != "" {
+		h.writePreconditionFailed(w, r, current)
+		return
+	}
+	problem.Write(w, r, problem.New(problem.CodeVersionConflict).WithDetail("detail.retry"))
+}
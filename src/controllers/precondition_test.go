@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"api3/src/models"
+	"api3/src/problem"
+	"api3/src/repository"
+	"api3/src/utils"
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// racingUsers simula otro cliente que guarda el mismo usuario entre la
+// lectura y la escritura del controlador
+type racingUsers struct {
+	repository.UserRepository
+}
+
+func (r *racingUsers) Update(ctx context.Context, user *models.User) error {
+	other, err := r.UserRepository.GetByID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	other.DisplayName = "Otro cliente"
+	if err := r.UserRepository.Update(ctx, other); err != nil {
+		return err
+	}
+	return r.UserRepository.Update(ctx, user)
+}
+
+func TestPatchPreconditions(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		ifMatch string
+		require bool // REQUIRE_IF_MATCH=true
+		racing  bool // otro cliente guarda antes
+		status  int
+		code    string
+		etag    string
+	}{
+		{"sin If-Match", "", false, false, http.StatusOK, "", `"2"`},
+		{"ETag vigente", `"1"`, false, false, http.StatusOK, "", `"2"`},
+		{"ETag viejo", `"7"`, false, false, http.StatusPreconditionFailed, problem.CodePreconditionFailed, `"1"`},
+		{"ETag débil", `W/"1"`, false, false, http.StatusPreconditionFailed, problem.CodePreconditionFailed, `"1"`},
+		{"cualquiera", `*`, false, false, http.StatusOK, "", `"2"`},
+		{"If-Match obligatorio ausente", "", true, false, http.StatusPreconditionRequired, problem.CodePreconditionRequired, ""},
+		{"If-Match obligatorio presente", `"1"`, true, false, http.StatusOK, "", `"2"`},
+		{"carrera sin If-Match", "", false, true, http.StatusConflict, problem.CodeVersionConflict, ""},
+		{"carrera con If-Match", `"1"`, false, true, http.StatusPreconditionFailed, problem.CodePreconditionFailed, `"2"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.require {
+				t.Setenv("REQUIRE_IF_MATCH", "true")
+			}
+			h := newTestHandler(t)
+			id := register(t, h, "ana", "norte")
+			if tt.racing {
+				h.users = &racingUsers{UserRepository: h.users}
+			}
+
+			req := request{method: "PATCH", target: "/api/v1/users/" + strconv.Itoa(id), vars: userVars(id), body: `{"zona":"sur"}`}
+			if tt.ifMatch != "" {
+				req.headers = []string{"If-Match", tt.ifMatch}
+			}
+			rec := serve(utils.RequireIfMatch(h.PatchUser), req)
+			if rec.Code != tt.status {
+				t.Fatalf("estado %d, se esperaba %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if got := rec.Header().Get("ETag"); got != tt.etag {
+				t.Errorf("ETag %q, se esperaba %q", got, tt.etag)
+			}
+			if tt.code == "" {
+				return
+			}
+			prob := decodeProblem(t, rec)
+			if prob.Code != tt.code {
+				t.Errorf("código %q, se esperaba %q", prob.Code, tt.code)
+			}
+			if tt.status == http.StatusPreconditionFailed && prob.Current == nil {
+				t.Error("el 412 no incluye el estado actual")
+			}
+
+			// Un cambio rechazado no se guarda
+			user, err := h.users.GetByID(context.Background(), id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.Zona != "norte" {
+				t.Errorf("se guardó la zona %q", user.Zona)
+			}
+		})
+	}
+}
+
+func TestDeletePreconditions(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		ifMatch string
+		changed bool // otro PATCH después de registrar
+		status  int
+		code    string
+	}{
+		{"ETag viejo", `"7"`, false, http.StatusPreconditionFailed, problem.CodePreconditionFailed},
+		{"ETag vigente", `"1"`, false, http.StatusOK, ""},
+		{"sin If-Match borra aunque haya cambiado", "", true, http.StatusOK, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t)
+			id := register(t, h, "ana", "norte")
+			if tt.changed {
+				rec := serve(h.PatchUser, request{method: "PATCH", target: "/api/v1/users/" + strconv.Itoa(id), vars: userVars(id), body: `{"zona":"sur"}`})
+				if rec.Code != http.StatusOK {
+					t.Fatalf("PATCH previo: %d %s", rec.Code, rec.Body)
+				}
+			}
+			req := request{method: "DELETE", target: "/api/v1/users/" + strconv.Itoa(id), vars: userVars(id)}
+			if tt.ifMatch != "" {
+				req.headers = []string{"If-Match", tt.ifMatch}
+			}
+			rec := serve(h.DeleteUser, req)
+			if rec.Code != tt.status {
+				t.Fatalf("estado %d, se esperaba %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.code != "" {
+				if prob := decodeProblem(t, rec); prob.Code != tt.code {
+					t.Errorf("código %q, se esperaba %q", prob.Code, tt.code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"api3/src/models"
+	"api3/src/problem"
+	"api3/src/repository"
+	"api3/src/search"
+	"api3/src/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// Campos buscables en el orden de searchDoc, con su peso en el ranking
+var searchFields = []search.Field{
+	{Name: "username", Weight: 3},
+	{Name: "displayName", Weight: 2},
+	{Name: "zona", Weight: 1},
+}
+
+// SearchResponse es la respuesta de GET /users/search. Source indica qué
+// índice respondió: fulltext (el de la BD) o memory (el del proceso).
+type SearchResponse struct {
+	Query  string      `json:"query"`
+	Source string      `json:"source"`
+	Data   []SearchHit `json:"data"`
+}
+
+// SearchHit es un usuario encontrado. Highlights tiene, por campo, el texto
+// con las coincidencias entre <mark> y </mark> (escapado como HTML).
+type SearchHit struct {
+	User       models.User       `json:"user"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+func searchDoc(user models.User) search.Doc {
+	user.Password = "" // el índice no guarda hashes
+	return search.Doc{ID: user.ID, Values: []string{user.Username, user.DisplayName, user.Zona}, Payload: user}
+}
+
+// Índice en memoria de todos los usuarios; se recarga cada SEARCH_INDEX_TTL
+// y tras cada cambio hecho por esta instancia
+func newSearchIndex(users repository.UserRepository) *search.Index {
+	return search.NewIndex(searchFields, utils.EnvDuration("SEARCH_INDEX_TTL", time.Minute), func(ctx context.Context) ([]search.Doc, error) {
+		var docs []search.Doc
+		err := users.Stream(ctx, repository.UserFilter{}, nil, func(user *models.User) error {
+			docs = append(docs, searchDoc(*user))
+			return nil
+		})
+		return docs, err
+	})
+}
+
+// SearchUsers busca usuarios por relevancia; usa el índice de texto
+// completo de la BD si existe (MySQL, PostgreSQL) y si no el de memoria
+func (h *UserHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := params.Get("q")
+	terms := search.Tokenize(query)
+	if len(terms) == 0 {
+		problem.Write(w, r, invalidParam("q", problem.FieldRequired))
+		return
+	}
+	limit := defaultSearchLimit
+	if raw := params.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			problem.Write(w, r, invalidParam("limit", problem.FieldOutOfRange).WithDetail("detail.limit_range", maxSearchLimit))
+			return
+		}
+		limit = n
+	}
+
+	resp := SearchResponse{Query: query, Source: "fulltext", Data: []SearchHit{}}
+	var hits []search.Hit
+	// Se piden más candidatos de los necesarios porque el ranking propio
+	// puede ordenarlos distinto que la BD
+	users, err := h.users.Search(r.Context(), terms, limit*2)
+	switch {
+	case err == nil:
+		docs := make([]search.Doc, len(users))
+		for i, user := range users {
+			docs[i] = searchDoc(user)
+		}
+		hits = search.Rank(searchFields, docs, query, limit)
+	case !errors.Is(err, repository.ErrSearchUnsupported):
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	// El texto completo de la BD no tolera errores de tipeo: si no encontró
+	// nada se intenta la búsqueda aproximada en memoria
+	if len(hits) == 0 {
+		resp.Source = "memory"
+		if hits, err = h.searchIndex.Search(r.Context(), query, limit); err != nil {
+			problem.Write(w, r, problem.Internal(err))
+			return
+		}
+	}
+
+	for _, hit := range hits {
+		user := hit.Doc.Payload.(models.User)
+		h.formatImage(r, &user)
+		resp.Data = append(resp.Data, SearchHit{User: user, Score: hit.Score, Highlights: hit.Highlights})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// changeNotifier avisa con changed después de cada escritura que tuvo éxito
+type changeNotifier struct {
+	repository.UserRepository
+	changed func()
+}
+
+func (n *changeNotifier) Create(ctx context.Context, user *models.User) error {
+	return n.notify(n.UserRepository.Create(ctx, user))
+}
+
+func (n *changeNotifier) Update(ctx context.Context, user *models.User) error {
+	return n.notify(n.UserRepository.Update(ctx, user))
+}
+
+func (n *changeNotifier) Delete(ctx context.Context, id int, version int64) error {
+	return n.notify(n.UserRepository.Delete(ctx, id, version))
+}
+
+func (n *changeNotifier) Transaction(ctx context.Context, fn func(tx repository.UserRepository) error) error {
+	return n.notify(n.UserRepository.Transaction(ctx, fn))
+}
+
+func (n *changeNotifier) notify(err error) error {
+	if err == nil {
+		n.changed()
+	}
+	return err
+}
@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"api3/src/events"
+	"api3/src/models"
+	"api3/src/problem"
+	"api3/src/repository"
+	"api3/src/utils"
+	"context"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Operaciones comunes a REST, GraphQL y gRPC; avisan de cada cambio a los
+// suscriptores de eventos. Devuelven un *problem.Problem,
+// salvo repository.ErrVersionConflict, que cada transporte traduce a su
+// manera (412 o 409 en REST).
+
+// listUsers devuelve una página de usuarios
+func (h *UserHandler) listUsers(ctx context.Context, q repository.ListQuery) (*repository.UserPage, error) {
+	page, err := h.users.List(ctx, q)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		return nil, problem.New(problem.CodeInvalidQuery).WithFields(map[string]string{"cursor": problem.FieldInvalidValue})
+	}
+	if err != nil {
+		return nil, problem.Internal(err)
+	}
+	return page, nil
+}
+
+// createUser valida los datos y crea el usuario con su imagen. El alta es
+// pública, pero crear un admin exige los claims de un admin en ctx
+func (h *UserHandler) createUser(ctx context.Context, input *RegisterInput) (*models.User, error) {
+	if input.Role == models.RoleAdmin {
+		if claims, ok := utils.ClaimsFrom(ctx); !ok || !strings.EqualFold(claims.Role, models.RoleAdmin) {
+			return nil, problem.New(problem.CodeForbidden)
+		}
+	}
+	if prob := h.validateInput(ctx, input, nil); prob != nil {
+		return nil, prob
+	}
+	if input.Role == "" {
+		input.Role = models.RoleUser
+	}
+
+	hashedPwd, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, problem.Internal(err)
+	}
+	user := &models.User{
+		Username:    input.Username,
+		DisplayName: strings.TrimSpace(input.DisplayName),
+		Password:    string(hashedPwd),
+		Role:        input.Role,
+		Zona:        input.Zona,
+		Status:      models.StatusActive,
+	}
+
+	if imageBytes := input.imageBytes(); len(imageBytes) > 0 {
+		key, err := h.storeImage(ctx, imageBytes)
+		if err != nil {
+			return nil, imageProblem(err)
+		}
+		user.ImageKey = key
+	}
+
+	if err := h.users.Create(ctx, user); err != nil {
+		h.deleteImage(user.ImageKey)
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, problem.New(problem.CodeUserExists).WithFields(map[string]string{"username": problem.FieldConflict})
+		}
+		return nil, problem.Internal(err)
+	}
+	h.publish(ctx, events.UserCreated, user, "")
+	return user, nil
+}
+
+// updateUser aplica los cambios ya validados y los guarda
+func (h *UserHandler) updateUser(ctx context.Context, user *models.User, p UserPatch) error {
+	if p.empty() {
+		return nil
+	}
+	prevZona := user.Zona
+
+	if p.Username != nil {
+		user.Username = strings.TrimSpace(*p.Username)
+	}
+	if p.DisplayName != nil {
+		user.DisplayName = strings.TrimSpace(*p.DisplayName)
+	}
+	if p.Role != nil {
+		user.Role = *p.Role
+	}
+	if p.Zona != nil {
+		user.Zona = *p.Zona
+	}
+	if p.Status != nil {
+		user.Status = *p.Status
+	}
+	if p.Password != nil {
+		hashed, err := bcrypt.GenerateFromPassword([]byte(*p.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return problem.Internal(err)
+		}
+		user.Password = string(hashed)
+	}
+
+	oldKey, newKey := "", ""
+	if p.ImageSet {
+		if p.Image != nil {
+			key, err := h.storeImage(ctx, p.Image)
+			if err != nil {
+				return imageProblem(err)
+			}
+			newKey = key
+		}
+		oldKey = user.ImageKey
+		user.ImageKey = newKey
+	}
+
+	if err := h.users.Update(ctx, user); err != nil {
+		h.deleteImage(newKey)
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return err
+		}
+		if errors.Is(err, repository.ErrDuplicate) {
+			return problem.New(problem.CodeUserExists).WithFields(map[string]string{"username": problem.FieldConflict})
+		}
+		return problem.Internal(err)
+	}
+
+	h.deleteImage(oldKey)
+	h.publish(ctx, events.UserUpdated, user, prevZona)
+	return nil
+}
+
+// deleteUser borra el usuario y libera su imagen. Con version 0 se borra
+// aunque haya cambiado desde la lectura.
+func (h *UserHandler) deleteUser(ctx context.Context, user *models.User, version int64) error {
+	if err := h.users.Delete(ctx, user.ID, version); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return err
+		}
+		return problem.Internal(err)
+	}
+	h.deleteImage(user.ImageKey)
+	h.publish(ctx, events.UserDeleted, user, "")
+	return nil
+}
+
+// userForChange busca el usuario a modificar. expected es la versión que
+// leyó el cliente (hace de If-Match); nil no la comprueba.
+func (h *UserHandler) userForChange(ctx context.Context, id int, expected *int64) (*models.User, error) {
+	user, err := h.users.GetByID(ctx, id)
+	if err != nil {
+		return nil, lookupProblem(err)
+	}
+	if expected != nil && *expected != user.Version {
+		return nil, preconditionFailed(user)
+	}
+	return user, nil
+}
+
+// changeFailed traduce el error de updateUser o deleteUser. Si chocó con
+// otro cambio es, como en REST, precondition_failed con el estado nuevo
+// cuando el cliente indicó la versión y version_conflict si no.
+func (h *UserHandler) changeFailed(ctx context.Context, id int, withVersion bool, err error) error {
+	if !errors.Is(err, repository.ErrVersionConflict) {
+		return err
+	}
+	if !withVersion {
+		return problem.New(problem.CodeVersionConflict).WithDetail("detail.retry")
+	}
+	current, err := h.users.GetByID(ctx, id)
+	if err != nil {
+		return lookupProblem(err)
+	}
+	return preconditionFailed(current)
+}
+
+func preconditionFailed(current *models.User) *problem.Problem {
+	current.FormatImageURL()
+	return problem.New(problem.CodePreconditionFailed).WithCurrent(current)
+}
+
+// asProblem devuelve el problema que lleva err o, si es un error
+// inesperado, uno interno que lo envuelve
+func asProblem(err error) *problem.Problem {
+	var prob *problem.Problem
+	if errors.As(err, &prob) {
+		return prob
+	}
+	return problem.Internal(err)
+}
@@ -1,334 +1,240 @@
 package controllers
 
 import (
-	"api3/db"
+	"api3/src/events"
+	"api3/src/i18n"
+	"api3/src/images"
 	"api3/src/models"
+	"api3/src/problem"
+	"api3/src/repository"
+	"api3/src/search"
 	"api3/src/utils"
-	"bytes"
-	"encoding/base64"
+	"api3/src/webhooks"
 	"encoding/json"
-	"io"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
-	"strings"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// Register godoc
-// @Summary Registrar nuevo usuario
-// @Description Crea un nuevo usuario en la base de datos
-// @Tags users
-// @Accept json
-// @Produce plain
-// @Param user body models.User true "Datos del nuevo usuario"
-// @Success 201 {string} string "Usuario creado"
-// @Failure 400 {string} string "Error al registrar usuario"
-// @Router /register [post]
-func Register(w http.ResponseWriter, r *http.Request) {
-	var username, password, role, zona string
-	var imageBytes []byte
-
-	contentType := r.Header.Get("Content-Type")
-	if strings.HasPrefix(contentType, "multipart/form-data") {
-		err := r.ParseMultipartForm(10 << 20)
-		if err != nil {
-			http.Error(w, "Error al parsear formulario: "+err.Error(), http.StatusBadRequest)
-			return
-		}
-
-		username = r.FormValue("username")
-		password = r.FormValue("password")
-		role = r.FormValue("role")
-		zona = r.FormValue("zona")
-		if role == "" {
-			role = "user"
-		}
-
-		file, _, err := r.FormFile("image")
-		if err == nil {
-			defer file.Close()
-			var buf bytes.Buffer
-			io.Copy(&buf, file)
-			imageBytes = buf.Bytes()
-		}
-
-	} else {
-		var input struct {
-			Username string `json:"username"`
-			Password string `json:"password"`
-			Role     string `json:"role"`
-			Zona     string `json:"zona"`
-			Image    string `json:"image"` // base64
-		}
-		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-			http.Error(w, "Error de JSON", http.StatusBadRequest)
-			return
-		}
-		username = input.Username
-		password = input.Password
-		role = input.Role
-		zona = input.Zona
+// UserHandler agrupa los controladores de usuarios y sus dependencias
+type UserHandler struct {
+	users       repository.UserRepository
+	imageStore  *images.Store
+	validate    *validator.Validate
+	searchIndex *search.Index
+	events      *events.Broker
+	webhooks    *webhooks.Dispatcher
+}
 
-		if input.Image != "" {
-			imageBytes, _ = base64.StdEncoding.DecodeString(input.Image)
-		}
-	}
+func NewUserHandler(users repository.UserRepository, zones repository.ZoneRepository, imageStore *images.Store, hooks *webhooks.Dispatcher) *UserHandler {
+	index := newSearchIndex(users)
+	// Cada escritura hecha por este proceso deja el índice de búsqueda al día
+	users = &changeNotifier{UserRepository: users, changed: index.Invalidate}
+	// EVENTS_HISTORY eventos quedan guardados para los clientes que reanudan
+	broker := events.NewBroker(utils.EnvInt("EVENTS_HISTORY", 1000))
+	return &UserHandler{users: users, imageStore: imageStore, validate: newValidator(zones), searchIndex: index, events: broker, webhooks: hooks}
+}
 
-	if username == "" || password == "" || zona == "" {
-		http.Error(w, "Faltan campos obligatorios", http.StatusBadRequest)
+// Register crea un usuario
+func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
+	var input RegisterInput
+	if prob := decodeInput(r, &input); prob != nil {
+		problem.Write(w, r, prob)
 		return
 	}
-
-	hashedPwd, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	user := models.User{
-		Username: username,
-		Password: string(hashedPwd),
-		Role:     role,
-		Zona:     zona,
-		Image:    imageBytes,
-	}
-
-	if err := db.DB.Create(&user).Error; err != nil {
-		http.Error(w, "Error al guardar usuario", http.StatusBadRequest)
+	user, err := h.createUser(r.Context(), &input)
+	if err != nil {
+		problem.Write(w, r, asProblem(err))
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	w.Write([]byte("Usuario creado"))
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/users/%d", user.ID))
+	w.Header().Set("ETag", user.ETag())
+	writeMessage(w, r, http.StatusCreated, "message.user_created")
 }
 
-
-
-// Login godoc
-// @Summary Iniciar sesión
-// @Description Autentica un usuario y devuelve un token JWT
-// @Tags auth
-// @Accept json
-// @Produce json
-// @Param user body models.User true "Credenciales de usuario"
-// @Success 200 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Router /login [post]
-func Login(w http.ResponseWriter, r *http.Request) {
-	var input struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+// Login comprueba las credenciales y devuelve un token JWT
+func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var input LoginInput
+	if prob := decodeInput(r, &input); prob != nil {
+		problem.Write(w, r, prob)
 		return
 	}
-
-	if input.Username == "" || input.Password == "" {
-		http.Error(w, "Username y password son obligatorios", http.StatusBadRequest)
+	if prob := h.validateInput(r.Context(), &input, nil); prob != nil {
+		problem.Write(w, r, prob)
 		return
 	}
 
-	var dbUser models.User
-	result := db.DB.Where("username = ?", input.Username).First(&dbUser)
-	if result.Error != nil {
-		http.Error(w, "Usuario no encontrado", http.StatusUnauthorized)
+	dbUser, err := h.users.GetByUsername(r.Context(), input.Username)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			problem.Write(w, r, problem.New(problem.CodeInvalidCredentials))
+			return
+		}
+		problem.Write(w, r, problem.Internal(err))
 		return
 	}
 
-	err := bcrypt.CompareHashAndPassword([]byte(dbUser.Password), []byte(input.Password))
+	err = bcrypt.CompareHashAndPassword([]byte(dbUser.Password), []byte(input.Password))
 	if err != nil {
-		http.Error(w, "Contraseña incorrecta", http.StatusUnauthorized)
+		problem.Write(w, r, problem.New(problem.CodeInvalidCredentials))
 		return
 	}
 
 	token, err := utils.GenerateToken(uint(dbUser.ID), dbUser.Role, dbUser.Zona)
 	if err != nil {
-		http.Error(w, "No se pudo generar token", http.StatusInternalServerError)
+		problem.Write(w, r, problem.Internal(err))
 		return
 	}
 
-	dbUser.FormatImage()
+	h.formatImage(r, dbUser)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"token":    token,
-		"username": dbUser.Username,
-		"role":     dbUser.Role,
-		"zona":     dbUser.Zona,
-		"image":    dbUser.ImageStr,
-		"imageType": dbUser.MimeType,
+	json.NewEncoder(w).Encode(LoginResponse{
+		Token:     token,
+		Username:  dbUser.Username,
+		Role:      dbUser.Role,
+		Zona:      dbUser.Zona,
+		Image:     dbUser.ImageStr,
+		ImageType: dbUser.MimeType,
+		ImageURL:  dbUser.ImageURL,
 	})
 }
 
+// GetAllUsers devuelve una página de usuarios filtrada y ordenada
+func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
+	q, prob := parseListQuery(r)
+	if prob != nil {
+		problem.Write(w, r, prob)
+		return
+	}
 
-
-
-// GetAllUsers godoc
-// @Summary Obtener todos los usuarios
-// @Description Retorna todos los usuarios registrados (requiere rol admin)
-// @Tags users
-// @Produce json
-// @Success 200 {array} models.User
-// @Failure 500 {object} map[string]string
-// @Router /users [get]
-func GetAllUsers(w http.ResponseWriter, r *http.Request) {
-	var users []models.User
-	if err := db.DB.Find(&users).Error; err != nil {
-		http.Error(w, "Error al obtener usuarios", http.StatusInternalServerError)
+	page, err := h.listUsers(r.Context(), q)
+	if err != nil {
+		problem.Write(w, r, asProblem(err))
 		return
 	}
 
 	// Formatear imágenes
-	for i := range users {
-		users[i].FormatImage()
+	for i := range page.Users {
+		h.formatImage(r, &page.Users[i])
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(users)
+	w.Header().Set("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	json.NewEncoder(w).Encode(newUserListResponse(r, q, page))
 }
 
+// GetUser devuelve un usuario por ID
+func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, r, problem.New(problem.CodeInvalidID))
+		return
+	}
 
+	user, err := h.users.GetByID(r.Context(), id)
+	h.writeUser(w, r, user, err)
+}
 
+// GetUserByUsername devuelve un usuario por nombre
+func (h *UserHandler) GetUserByUsername(w http.ResponseWriter, r *http.Request) {
+	user, err := h.users.GetByUsername(r.Context(), mux.Vars(r)["username"])
+	h.writeUser(w, r, user, err)
+}
 
-// UpdateUser godoc
-// @Summary Actualizar usuario
-// @Description Actualiza los datos de un usuario existente (requiere rol dev)
-// @Tags users
-// @Accept json
-// @Produce plain
-// @Param id path int true "ID del usuario"
-// @Param user body models.User true "Datos actualizados"
-// @Success 200 {string} string "Usuario actualizado"
-// @Failure 404 {string} string "Usuario no encontrado"
-// @Router /update/{id} [put]
-func UpdateUser(w http.ResponseWriter, r *http.Request) {
-	idParam := mux.Vars(r)["id"]
-	id, err := strconv.Atoi(idParam)
+// Responde con un usuario o con el error de la búsqueda
+func (h *UserHandler) writeUser(w http.ResponseWriter, r *http.Request, user *models.User, err error) {
 	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+		problem.Write(w, r, lookupProblem(err))
 		return
 	}
 
-	var user models.User
-	if err := db.DB.First(&user, id).Error; err != nil {
-		http.Error(w, "Usuario no encontrado", http.StatusNotFound)
+	w.Header().Set("ETag", user.ETag())
+	if ifNoneMatch(r, user) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
-	contentType := r.Header.Get("Content-Type")
-
-	var username, role, password, zona string
-	var imageBase64 string
-	imageUpdated := false
+	h.formatImage(r, user)
 
-	if strings.HasPrefix(contentType, "multipart/form-data") {
-		err := r.ParseMultipartForm(10 << 20)
-		if err != nil {
-			http.Error(w, "No se pudo parsear el formulario: "+err.Error(), http.StatusBadRequest)
-			return
-		}
-
-		username = r.FormValue("username")
-		role = r.FormValue("role")
-		password = r.FormValue("password")
-		zona = r.FormValue("zona")
-
-		file, _, err := r.FormFile("image")
-		if err == nil {
-			defer file.Close()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
 
-			var buf bytes.Buffer
-			if _, err := io.Copy(&buf, file); err != nil {
-				http.Error(w, "No se pudo leer la imagen: "+err.Error(), http.StatusInternalServerError)
-				return
-			}
-			imageBase64 = base64.StdEncoding.EncodeToString(buf.Bytes())
-			imageUpdated = true
-		} else {
-			if err != http.ErrMissingFile {
-				http.Error(w, "Error al procesar imagen: "+err.Error(), http.StatusBadRequest)
-				return
-			}
-		}
-	} else {
-		var input struct {
-			Username string `json:"username"`
-			Password string `json:"password"`
-			Role     string `json:"role"`
-			Zona     string `json:"zona"`
-			Image    string `json:"image"` // Para actualizar imagen desde JSON
-		}
-		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-			http.Error(w, "Error en el formato JSON: "+err.Error(), http.StatusBadRequest)
-			return
-		}
-		username = input.Username
-		password = input.Password
-		role = input.Role
-		zona = input.Zona
+// Responde con un mensaje de texto del catálogo en el idioma de la petición
+func writeMessage(w http.ResponseWriter, r *http.Request, status int, key string) {
+	locale := i18n.FromRequest(r)
+	i18n.SetHeaders(w, locale)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write([]byte(i18n.T(locale, key)))
+}
 
-		if input.Image != "" {
-			imageBase64 = input.Image
-			imageUpdated = true
-		}
+// UpdateUser atiende la ruta obsoleta PUT /update/{id}: cambia los campos
+// no vacíos
+func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.userForUpdate(w, r)
+	if !ok {
+		return
 	}
 
-	updates := map[string]interface{}{}
-
-	if username != "" {
-		updates["username"] = username
-	}
-	if role != "" {
-		updates["role"] = role
+	var input LegacyUpdateInput
+	if prob := decodeInput(r, &input); prob != nil {
+		problem.Write(w, r, prob)
+		return
 	}
-	if zona != "" {
-		updates["zona"] = zona
+	if prob := h.validateInput(r.Context(), &input, nil); prob != nil {
+		problem.Write(w, r, prob)
+		return
 	}
-	if password != "" {
-		hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-		if err != nil {
-			http.Error(w, "Error al encriptar la contraseña", http.StatusInternalServerError)
-			return
+
+	// En esta ruta un campo vacío significa "sin cambios"
+	var patch UserPatch
+	for _, f := range []struct {
+		value string
+		dst   **string
+	}{{input.Username, &patch.Username}, {input.Role, &patch.Role}, {input.Zona, &patch.Zona}, {input.Status, &patch.Status}, {input.Password, &patch.Password}} {
+		if f.value != "" {
+			value := f.value
+			*f.dst = &value
 		}
-		updates["password"] = string(hashed)
 	}
-	if imageUpdated {
-        decodedImage, err := base64.StdEncoding.DecodeString(imageBase64)
-        if err != nil {
-        	http.Error(w, "Error al decodificar imagen", http.StatusBadRequest)
-        	return
-        }
-        updates["image"] = decodedImage
+	if image := input.imageBytes(); image != nil {
+		patch.Image, patch.ImageSet = image, true
 	}
 
-	if len(updates) > 0 {
-		if err := db.DB.Model(&user).Updates(updates).Error; err != nil {
-			http.Error(w, "Error al actualizar usuario: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
+	if !h.saveChanges(w, r, user, patch) {
+		return
 	}
-
-	w.Write([]byte("Usuario actualizado"))
+	writeMessage(w, r, http.StatusOK, "message.user_updated")
 }
 
+// DeleteUser elimina un usuario y libera su imagen
+func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.userForUpdate(w, r)
+	if !ok {
+		return
+	}
 
-
-
-// DeleteUser godoc
-// @Summary Eliminar usuario
-// @Description Elimina un usuario de la base de datos (requiere rol dev)
-// @Tags users
-// @Produce plain
-// @Param id path int true "ID del usuario"
-// @Success 200 {string} string "Usuario eliminado"
-// @Failure 500 {string} string "Error al eliminar usuario"
-// @Router /delete/{id} [delete]
-func DeleteUser(w http.ResponseWriter, r *http.Request) {
-	idParam := mux.Vars(r)["id"]
-	id, _ := strconv.Atoi(idParam)
-
-	if err := db.DB.Delete(&models.User{}, id).Error; err != nil {
-		http.Error(w, "Error al eliminar usuario", http.StatusInternalServerError)
+	// Sin If-Match se borra aunque haya cambiado desde la lectura
+	var version int64
+	if r.Header.Get("If-Match") != "" {
+		version = user.Version
+	}
+	if err := h.deleteUser(r.Context(), user, version); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			h.writeVersionConflict(w, r, user.ID)
+			return
+		}
+		problem.Write(w, r, asProblem(err))
 		return
 	}
 
-	w.Write([]byte("Usuario eliminado"))
+	writeMessage(w, r, http.StatusOK, "message.user_deleted")
 }
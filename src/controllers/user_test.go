@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"api3/src/images"
+	"api3/src/models"
+	"api3/src/problem"
+	"api3/src/repository"
+	"api3/src/storage"
+	"api3/src/utils"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// newTestHandler monta el controlador sobre los repositorios en memoria con
+// las zonas norte y sur
+func newTestHandler(t *testing.T) *UserHandler {
+	t.Helper()
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	zones := repository.NewMemoryZoneRepository()
+	if err := repository.EnsureZones(context.Background(), zones, []string{"norte", "sur"}); err != nil {
+		t.Fatal(err)
+	}
+	store := images.NewStore(blobs, repository.NewMemoryImageRepository())
+	return NewUserHandler(repository.NewMemoryUserRepository(), zones, store, nil)
+}
+
+// request es una petición a un controlador; vars son las variables de la
+// ruta y headers pares nombre, valor
+type request struct {
+	method  string
+	target  string
+	vars    map[string]string
+	body    string
+	headers []string
+}
+
+func serve(handler http.HandlerFunc, req request) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(req.method, req.target, strings.NewReader(req.body))
+	if req.body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(req.headers); i += 2 {
+		r.Header.Set(req.headers[i], req.headers[i+1])
+	}
+	if req.vars != nil {
+		r = mux.SetURLVars(r, req.vars)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, r)
+	return rec
+}
+
+// register crea un usuario y devuelve su ID
+func register(t *testing.T, h *UserHandler, username, zona string) int {
+	t.Helper()
+	body := `{"username":"` + username + `","password":"secreto123","zona":"` + zona + `"}`
+	rec := serve(h.Register, request{method: "POST", target: "/api/v1/users", body: body})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("registrar %s: %d %s", username, rec.Code, rec.Body)
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(rec.Header().Get("Location"), "/api/v1/users/"))
+	if err != nil {
+		t.Fatalf("Location %q", rec.Header().Get("Location"))
+	}
+	return id
+}
+
+func userVars(id int) map[string]string {
+	return map[string]string{"id": strconv.Itoa(id)}
+}
+
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) problem.Problem {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/problem+json") {
+		t.Fatalf("Content-Type %q, se esperaba problem+json (%d %s)", ct, rec.Code, rec.Body)
+	}
+	var prob problem.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &prob); err != nil {
+		t.Fatal(err)
+	}
+	return prob
+}
+
+// fieldCodes devuelve el código de cada campo rechazado
+func fieldCodes(prob problem.Problem) map[string]string {
+	codes := map[string]string{}
+	for _, fe := range prob.Errors {
+		codes[fe.Field] = fe.Code
+	}
+	return codes
+}
+
+func TestRegister(t *testing.T) {
+	h := newTestHandler(t)
+	register(t, h, "ana", "norte")
+
+	for _, tt := range []struct {
+		name   string
+		body   string
+		status int
+		code   string
+		fields map[string]string
+	}{
+		{"válido", `{"username":"bea","password":"secreto123","zona":"sur","displayName":" Bea "}`, http.StatusCreated, "", nil},
+		{"repetido", `{"username":"ana","password":"secreto123","zona":"norte"}`, http.StatusConflict, problem.CodeUserExists, map[string]string{"username": problem.FieldConflict}},
+		{"zona desconocida", `{"username":"carla","password":"secreto123","zona":"este"}`, http.StatusUnprocessableEntity, problem.CodeValidation, map[string]string{"zona": problem.FieldUnknownZona}},
+		{"sin datos", `{}`, http.StatusUnprocessableEntity, problem.CodeValidation, map[string]string{"username": problem.FieldRequired, "password": problem.FieldRequired, "zona": problem.FieldRequired}},
+		{"JSON roto", `{"username":`, http.StatusBadRequest, problem.CodeInvalidBody, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(h.Register, request{method: "POST", target: "/api/v1/users", body: tt.body})
+			if rec.Code != tt.status {
+				t.Fatalf("estado %d, se esperaba %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.code == "" {
+				if rec.Header().Get("Location") == "" || rec.Header().Get("ETag") != `"1"` {
+					t.Errorf("cabeceras Location=%q ETag=%q", rec.Header().Get("Location"), rec.Header().Get("ETag"))
+				}
+				return
+			}
+			prob := decodeProblem(t, rec)
+			if prob.Code != tt.code {
+				t.Errorf("código %q, se esperaba %q", prob.Code, tt.code)
+			}
+			if tt.fields != nil && !equalCodes(fieldCodes(prob), tt.fields) {
+				t.Errorf("campos %v, se esperaba %v", fieldCodes(prob), tt.fields)
+			}
+		})
+	}
+}
+
+// El alta es pública, pero crear un admin exige un token de admin
+func TestRegisterAdmin(t *testing.T) {
+	token := func(role string) string {
+		tok, err := utils.GenerateToken(1, role, "norte")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "?token=" + tok
+	}
+	for _, tt := range []struct {
+		name   string
+		query  string
+		role   string
+		status int
+	}{
+		{"anónimo como user", "", models.RoleUser, http.StatusCreated},
+		{"anónimo como admin", "", models.RoleAdmin, http.StatusForbidden},
+		{"user crea un admin", token(models.RoleUser), models.RoleAdmin, http.StatusForbidden},
+		{"admin crea un admin", token(models.RoleAdmin), models.RoleAdmin, http.StatusCreated},
+		{"token inválido", "?token=x", models.RoleUser, http.StatusUnauthorized},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t)
+			body := `{"username":"bea","password":"secreto123","zona":"sur","role":"` + tt.role + `"}`
+			rec := serve(utils.OptionalAuth(h.Register), request{method: "POST", target: "/api/v1/users" + tt.query, body: body})
+			if rec.Code != tt.status {
+				t.Fatalf("estado %d, se esperaba %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			_, err := h.users.GetByUsername(context.Background(), "bea")
+			if created := err == nil; created != (tt.status == http.StatusCreated) {
+				t.Errorf("creado %v con estado %d", created, rec.Code)
+			}
+		})
+	}
+}
+
+func equalCodes(got, want map[string]string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for k, v := range want {
+		if got[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGetUser(t *testing.T) {
+	h := newTestHandler(t)
+	id := register(t, h, "ana", "norte")
+
+	for _, tt := range []struct {
+		name   string
+		id     string
+		status int
+		code   string
+	}{
+		{"existe", strconv.Itoa(id), http.StatusOK, ""},
+		{"no existe", "999", http.StatusNotFound, problem.CodeUserNotFound},
+		{"ID inválido", "abc", http.StatusBadRequest, problem.CodeInvalidID},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(h.GetUser, request{method: "GET", target: "/api/v1/users/" + tt.id, vars: map[string]string{"id": tt.id}})
+			if rec.Code != tt.status {
+				t.Fatalf("estado %d, se esperaba %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.code != "" {
+				if prob := decodeProblem(t, rec); prob.Code != tt.code {
+					t.Errorf("código %q, se esperaba %q", prob.Code, tt.code)
+				}
+				return
+			}
+			var user map[string]interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &user); err != nil {
+				t.Fatal(err)
+			}
+			if user["username"] != "ana" || user["zona"] != "norte" || user["role"] != models.RoleUser {
+				t.Errorf("usuario %v", user)
+			}
+			if _, ok := user["password"]; ok {
+				t.Error("la respuesta incluye la contraseña")
+			}
+		})
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	h := newTestHandler(t)
+	for _, u := range []struct{ name, zona string }{{"ana", "norte"}, {"bea", "sur"}, {"carla", "norte"}} {
+		register(t, h, u.name, u.zona)
+	}
+
+	for _, tt := range []struct {
+		query  string
+		status int
+		total  int64
+		names  []string
+	}{
+		{"", http.StatusOK, 3, []string{"ana", "bea", "carla"}},
+		{"?zona=norte&sort=-username", http.StatusOK, 2, []string{"carla", "ana"}},
+		{"?limit=1&offset=1", http.StatusOK, 3, []string{"bea"}},
+		{"?limit=0", http.StatusBadRequest, 0, nil},
+		{"?sort=password", http.StatusBadRequest, 0, nil},
+	} {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := serve(h.GetAllUsers, request{method: "GET", target: "/api/v1/users" + tt.query})
+			if rec.Code != tt.status {
+				t.Fatalf("estado %d, se esperaba %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status != http.StatusOK {
+				decodeProblem(t, rec)
+				return
+			}
+			var page UserListResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, u := range page.Data {
+				names = append(names, u.Username)
+			}
+			if page.Total != tt.total || strings.Join(names, ",") != strings.Join(tt.names, ",") {
+				t.Errorf("total %d %v, se esperaba %d %v", page.Total, names, tt.total, tt.names)
+			}
+			if rec.Header().Get("X-Total-Count") != strconv.FormatInt(tt.total, 10) {
+				t.Errorf("X-Total-Count = %q", rec.Header().Get("X-Total-Count"))
+			}
+		})
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	h := newTestHandler(t)
+	id := register(t, h, "ana", "norte")
+
+	for _, tt := range []struct {
+		name   string
+		id     int
+		status int
+		code   string
+	}{
+		{"existe", id, http.StatusOK, ""},
+		{"ya borrado", id, http.StatusNotFound, problem.CodeUserNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(h.DeleteUser, request{method: "DELETE", target: "/api/v1/users/" + strconv.Itoa(tt.id), vars: userVars(tt.id)})
+			if rec.Code != tt.status {
+				t.Fatalf("estado %d, se esperaba %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.code != "" {
+				if prob := decodeProblem(t, rec); prob.Code != tt.code {
+					t.Errorf("código %q, se esperaba %q", prob.Code, tt.code)
+				}
+			} else if rec.Body.String() != "Usuario eliminado" {
+				t.Errorf("cuerpo %q", rec.Body)
+			}
+		})
+	}
+
+	rec := serve(h.GetUser, request{method: "GET", target: "/api/v1/users/" + strconv.Itoa(id), vars: userVars(id)})
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("tras borrar, GET responde %d", rec.Code)
+	}
+}
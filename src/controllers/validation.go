@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"api3/src/events"
+	"api3/src/models"
+	"api3/src/problem"
+	"api3/src/repository"
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// newValidator prepara las reglas propias de la API: username, role,
+// status, zona (que debe existir en zones) y event (tipo de evento)
+func newValidator(zones repository.ZoneRepository) *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Los errores usan el nombre JSON del campo
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	v.RegisterValidation("username", func(fl validator.FieldLevel) bool {
+		return usernamePattern.MatchString(fl.Field().String())
+	})
+	v.RegisterValidation("role", func(fl validator.FieldLevel) bool {
+		return models.ValidRole(fl.Field().String())
+	})
+	v.RegisterValidation("status", func(fl validator.FieldLevel) bool {
+		return models.ValidStatus(fl.Field().String())
+	})
+	v.RegisterValidation("event", func(fl validator.FieldLevel) bool {
+		switch fl.Field().String() {
+		case events.UserCreated, events.UserUpdated, events.UserDeleted:
+			return true
+		}
+		return false
+	})
+	v.RegisterValidationCtx("zona", func(ctx context.Context, fl validator.FieldLevel) bool {
+		exists, err := zones.Exists(ctx, fl.Field().String())
+		if err != nil {
+			lookupFailed(ctx, err)
+		}
+		return exists
+	})
+	return v
+}
+
+// Las reglas que consultan la BD no pueden devolver un error; lo dejan en
+// el contexto para que validateWith responda 500 y no un error de campo
+type lookupFailure struct{ err error }
+
+type lookupFailureKey struct{}
+
+func lookupFailed(ctx context.Context, err error) {
+	if f, ok := ctx.Value(lookupFailureKey{}).(*lookupFailure); ok && f.err == nil {
+		f.err = err
+	}
+}
+
+// Valida dto y junta sus errores con los ya detectados al decodificar
+// (structural); devuelve nil si no hay ninguno
+func (h *UserHandler) validateInput(ctx context.Context, dto interface{}, structural fieldErrors) *problem.Problem {
+	return validateWith(ctx, h.validate, dto, structural)
+}
+
+func validateWith(ctx context.Context, v *validator.Validate, dto interface{}, structural fieldErrors) *problem.Problem {
+	var errs []problem.FieldError
+	names := make([]string, 0, len(structural))
+	for name := range structural {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		errs = append(errs, problem.Field(name, structural[name]))
+	}
+
+	failure := &lookupFailure{}
+	err := v.StructCtx(context.WithValue(ctx, lookupFailureKey{}, failure), dto)
+	if failure.err != nil {
+		return problem.Internal(failure.err)
+	}
+	if err != nil {
+		var verrs validator.ValidationErrors
+		if !errors.As(err, &verrs) {
+			return problem.Internal(err)
+		}
+		for _, fe := range verrs {
+			if _, seen := structural[fe.Field()]; seen {
+				continue
+			}
+			errs = append(errs, fieldError(fe))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return problem.New(problem.CodeValidation).WithFieldErrors(errs...)
+}
+
+// Traduce una regla incumplida a un código de error de campo
+func fieldError(fe validator.FieldError) problem.FieldError {
+	name := fe.Field()
+	switch fe.Tag() {
+	case "required":
+		return problem.Field(name, problem.FieldRequired)
+	case "min":
+		n, _ := strconv.Atoi(fe.Param())
+		if n <= 1 {
+			return problem.Field(name, problem.FieldEmpty)
+		}
+		return problem.Field(name, problem.FieldTooShort, n)
+	case "max":
+		n, _ := strconv.Atoi(fe.Param())
+		return problem.Field(name, problem.FieldTooLong, n)
+	case "base64":
+		return problem.Field(name, problem.FieldInvalidBase64)
+	case "username":
+		return problem.Field(name, problem.FieldInvalidFormat)
+	case "role":
+		return problem.Field(name, problem.FieldInvalidRole)
+	case "status":
+		return problem.Field(name, problem.FieldInvalidStatus)
+	case "zona":
+		return problem.Field(name, problem.FieldUnknownZona)
+	case "http_url":
+		return problem.Field(name, problem.FieldInvalidURL)
+	case "event":
+		return problem.Field(name, problem.FieldInvalidEvent)
+	}
+	return problem.Field(name, problem.FieldInvalidValue)
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"api3/src/events"
+	"api3/src/problem"
+	"api3/src/repository"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// brokenZones falla al consultar, como una BD caída
+type brokenZones struct {
+	repository.ZoneRepository
+}
+
+func (brokenZones) Exists(ctx context.Context, name string) (bool, error) {
+	return false, errors.New("conexión rechazada")
+}
+
+// Si no se puede comprobar la zona es un 500, no un error del cliente
+func TestValidateZonaLookupError(t *testing.T) {
+	v := newValidator(brokenZones{})
+	prob := validateWith(context.Background(), v, &RegisterInput{Username: "ana", Password: "secreto123", Zona: "norte"}, nil)
+	if prob == nil || prob.Code != problem.CodeInternal || prob.Status != http.StatusInternalServerError {
+		t.Fatalf("problema %+v, se esperaba %s", prob, problem.CodeInternal)
+	}
+	if len(prob.Errors) != 0 {
+		t.Errorf("errores de campo %v", prob.Errors)
+	}
+}
+
+func TestValidateDTOs(t *testing.T) {
+	zones := repository.NewMemoryZoneRepository()
+	if err := repository.EnsureZones(context.Background(), zones, []string{"norte", "sur"}); err != nil {
+		t.Fatal(err)
+	}
+	v := newValidator(zones)
+	str := func(s string) *string { return &s }
+
+	for _, tt := range []struct {
+		name       string
+		dto        interface{}
+		structural fieldErrors
+		want       map[string]string // nil: válido
+	}{
+		{"registro válido", &RegisterInput{Username: "ana.b_1", Password: "secreto123", Role: "admin", Zona: "norte"}, nil, nil},
+		{"registro vacío", &RegisterInput{}, nil, map[string]string{
+			"username": problem.FieldRequired, "password": problem.FieldRequired, "zona": problem.FieldRequired,
+		}},
+		{"todos los errores juntos", &RegisterInput{
+			Username: "a b", DisplayName: strings.Repeat("x", 101), Password: "123", Role: "root", Zona: "este",
+			imageInput: imageInput{Image: "%%%"},
+		}, nil, map[string]string{
+			"username": problem.FieldInvalidFormat, "displayName": problem.FieldTooLong, "password": problem.FieldTooShort,
+			"role": problem.FieldInvalidRole, "zona": problem.FieldUnknownZona, "image": problem.FieldInvalidBase64,
+		}},
+		{"username corto y contraseña larga", &RegisterInput{Username: "ab", Password: strings.Repeat("x", 73), Zona: "sur"}, nil, map[string]string{
+			"username": problem.FieldTooShort, "password": problem.FieldTooLong,
+		}},
+		{"reemplazo sin rol y estado desconocido", &ReplaceUserInput{Username: "ana", Zona: "sur", Status: "dormido"}, nil, map[string]string{
+			"role": problem.FieldRequired, "status": problem.FieldInvalidStatus,
+		}},
+		{"parche con zona vacía", &UserPatch{Zona: str("")}, nil, map[string]string{"zona": problem.FieldEmpty}},
+		{"parche vacío", &UserPatch{}, nil, nil},
+		{"los errores al decodificar tienen prioridad", &UserPatch{Username: str("x")}, fieldErrors{"username": problem.FieldNull, "edad": problem.FieldUnknown}, map[string]string{
+			"username": problem.FieldNull, "edad": problem.FieldUnknown,
+		}},
+		{"webhook", &WebhookInput{URL: "ftp://ejemplo.com", Events: []string{events.UserCreated, "user.renamed"}, Secret: "corto"}, nil, map[string]string{
+			"url": problem.FieldInvalidURL, "events[1]": problem.FieldInvalidEvent, "secret": problem.FieldTooShort,
+		}},
+		{"zona sin nombre", &ZoneInput{}, nil, map[string]string{"name": problem.FieldRequired}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			prob := validateWith(context.Background(), v, tt.dto, tt.structural)
+			if tt.want == nil {
+				if prob != nil {
+					t.Fatalf("problema %+v", prob)
+				}
+				return
+			}
+			if prob == nil {
+				t.Fatal("se aceptó")
+			}
+			if prob.Code != problem.CodeValidation || prob.Status != http.StatusUnprocessableEntity {
+				t.Errorf("problema %s (%d), se esperaba %s", prob.Code, prob.Status, problem.CodeValidation)
+			}
+			if got := fieldCodes(*prob); !equalCodes(got, tt.want) || len(prob.Errors) != len(tt.want) {
+				t.Errorf("campos %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package controllers
+
+import (
+	"api3/src/models"
+	"api3/src/problem"
+	"api3/src/repository"
+	"api3/src/webhooks"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+// WebhookHandler administra las suscripciones de otros sistemas a los
+// cambios de usuarios y sus entregas
+type WebhookHandler struct {
+	hooks      repository.WebhookRepository
+	dispatcher *webhooks.Dispatcher
+	validate   *validator.Validate
+}
+
+func NewWebhookHandler(hooks repository.WebhookRepository, dispatcher *webhooks.Dispatcher) *WebhookHandler {
+	// Los webhooks no llevan zona: la regla zona no se usa
+	return &WebhookHandler{hooks: hooks, dispatcher: dispatcher, validate: newValidator(nil)}
+}
+
+// WebhookInput son los datos para crear un webhook. Sin secret se genera uno.
+type WebhookInput struct {
+	URL         string   `json:"url" validate:"required,max=2000,http_url"`
+	Events      []string `json:"events" validate:"dive,event"`
+	Description string   `json:"description" validate:"max=200"`
+	Active      *bool    `json:"active"`
+	Secret      string   `json:"secret" validate:"omitempty,min=16,max=200"`
+}
+
+// WebhookPatch cambia los campos presentes; rotateSecret genera un secreto
+// nuevo, que se devuelve en la respuesta
+type WebhookPatch struct {
+	URL          *string   `json:"url" validate:"omitnil,max=2000,http_url"`
+	Events       *[]string `json:"events" validate:"omitnil,dive,event"`
+	Description  *string   `json:"description" validate:"omitnil,max=200"`
+	Active       *bool     `json:"active"`
+	RotateSecret bool      `json:"rotateSecret"`
+}
+
+// WebhookSecret es un webhook con su secreto, que solo se muestra al
+// crearlo o al cambiarlo
+type WebhookSecret struct {
+	models.Webhook
+	Secret string `json:"secret,omitempty"`
+}
+
+// ListWebhooks devuelve los webhooks (sin sus secretos)
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := h.hooks.List(r.Context())
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, hooks)
+}
+
+// CreateWebhook registra un webhook
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var input WebhookInput
+	if prob := decodeJSON(r, &input); prob != nil {
+		problem.Write(w, r, prob)
+		return
+	}
+	input.URL = strings.TrimSpace(input.URL)
+	if prob := validateWith(r.Context(), h.validate, &input, nil); prob != nil {
+		problem.Write(w, r, prob)
+		return
+	}
+
+	hook := models.Webhook{URL: input.URL, Events: input.Events, Description: strings.TrimSpace(input.Description), Active: true, Secret: input.Secret}
+	if input.Active != nil {
+		hook.Active = *input.Active
+	}
+	if hook.Secret == "" {
+		secret, err := webhooks.NewSecret()
+		if err != nil {
+			problem.Write(w, r, problem.Internal(err))
+			return
+		}
+		hook.Secret = secret
+	}
+	if err := h.hooks.Create(r.Context(), &hook); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+	w.Header().Set("Location", "/api/v1/webhooks/"+strconv.Itoa(hook.ID))
+	writeJSON(w, http.StatusCreated, WebhookSecret{Webhook: hook, Secret: hook.Secret})
+}
+
+// GetWebhook devuelve un webhook
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, prob := h.webhookFromPath(r)
+	if prob != nil {
+		problem.Write(w, r, prob)
+		return
+	}
+	writeJSON(w, http.StatusOK, hook)
+}
+
+// UpdateWebhook cambia un webhook
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, prob := h.webhookFromPath(r)
+	if prob != nil {
+		problem.Write(w, r, prob)
+		return
+	}
+	var patch WebhookPatch
+	if prob := decodeJSON(r, &patch); prob != nil {
+		problem.Write(w, r, prob)
+		return
+	}
+	if patch.URL != nil {
+		*patch.URL = strings.TrimSpace(*patch.URL)
+	}
+	if prob := validateWith(r.Context(), h.validate, &patch, nil); prob != nil {
+		problem.Write(w, r, prob)
+		return
+	}
+
+	if patch.URL != nil {
+		hook.URL = *patch.URL
+	}
+	if patch.Events != nil {
+		hook.Events = *patch.Events
+	}
+	if patch.Description != nil {
+		hook.Description = strings.TrimSpace(*patch.Description)
+	}
+	if patch.Active != nil {
+		hook.Active = *patch.Active
+	}
+	if patch.RotateSecret {
+		secret, err := webhooks.NewSecret()
+		if err != nil {
+			problem.Write(w, r, problem.Internal(err))
+			return
+		}
+		hook.Secret = secret
+	}
+	if err := h.hooks.Update(r.Context(), hook); err != nil {
+		problem.Write(w, r, webhookProblem(err))
+		return
+	}
+	if patch.RotateSecret {
+		writeJSON(w, http.StatusOK, WebhookSecret{Webhook: *hook, Secret: hook.Secret})
+		return
+	}
+	writeJSON(w, http.StatusOK, hook)
+}
+
+// DeleteWebhook borra un webhook con sus entregas
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, r, problem.New(problem.CodeInvalidID))
+		return
+	}
+	if err := h.hooks.Delete(r.Context(), id); err != nil {
+		problem.Write(w, r, webhookProblem(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries devuelve el registro de entregas de un webhook
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	hook, prob := h.webhookFromPath(r)
+	if prob != nil {
+		problem.Write(w, r, prob)
+		return
+	}
+	filter, prob := deliveryFilter(r)
+	if prob != nil {
+		problem.Write(w, r, prob)
+		return
+	}
+	filter.WebhookID = hook.ID
+	h.writeDeliveries(w, r, filter)
+}
+
+// ListDeadLetters devuelve las entregas que agotaron sus intentos, de todos
+// los webhooks
+func (h *WebhookHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	filter, prob := deliveryFilter(r)
+	if prob != nil {
+		problem.Write(w, r, prob)
+		return
+	}
+	filter.Status = models.DeliveryDead
+	h.writeDeliveries(w, r, filter)
+}
+
+func (h *WebhookHandler) writeDeliveries(w http.ResponseWriter, r *http.Request, filter repository.DeliveryFilter) {
+	deliveries, err := h.hooks.Deliveries(r.Context(), filter)
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// GetDelivery devuelve una entrega con el registro de sus intentos
+func (h *WebhookHandler) GetDelivery(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		problem.Write(w, r, problem.New(problem.CodeInvalidID))
+		return
+	}
+	delivery, err := h.hooks.GetDelivery(r.Context(), id)
+	if err != nil {
+		problem.Write(w, r, webhookProblem(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, delivery)
+}
+
+// Redeliver vuelve a enviar una entrega, p. ej. una de la lista de fallidas
+// cuando el receptor ya funciona
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		problem.Write(w, r, problem.New(problem.CodeInvalidID))
+		return
+	}
+	delivery, err := h.dispatcher.Redeliver(r.Context(), id)
+	if err != nil {
+		problem.Write(w, r, webhookProblem(err))
+		return
+	}
+	writeJSON(w, http.StatusAccepted, delivery)
+}
+
+func (h *WebhookHandler) webhookFromPath(r *http.Request) (*models.Webhook, *problem.Problem) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, problem.New(problem.CodeInvalidID)
+	}
+	hook, err := h.hooks.Get(r.Context(), id)
+	if err != nil {
+		return nil, webhookProblem(err)
+	}
+	return hook, nil
+}
+
+func deliveryFilter(r *http.Request) (repository.DeliveryFilter, *problem.Problem) {
+	params := r.URL.Query()
+	filter := repository.DeliveryFilter{Status: params.Get("status"), Limit: defaultDeliveryLimit}
+	switch filter.Status {
+	case "", models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead:
+	default:
+		return filter, invalidParam("status", problem.FieldInvalidValue)
+	}
+	if raw := params.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxDeliveryLimit {
+			return filter, invalidParam("limit", problem.FieldOutOfRange).WithDetail("detail.limit_range", maxDeliveryLimit)
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+func webhookProblem(err error) *problem.Problem {
+	switch {
+	case errors.Is(err, repository.ErrWebhookNotFound):
+		return problem.New(problem.CodeWebhookNotFound)
+	case errors.Is(err, repository.ErrDeliveryNotFound):
+		return problem.New(problem.CodeDeliveryNotFound)
+	}
+	return problem.Internal(err)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
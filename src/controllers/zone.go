@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"api3/src/models"
+	"api3/src/problem"
+	"api3/src/repository"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ZoneHandler gestiona el catálogo de zonas que pueden asignarse a usuarios
+type ZoneHandler struct {
+	zones    repository.ZoneRepository
+	validate *validator.Validate
+}
+
+func NewZoneHandler(zones repository.ZoneRepository) *ZoneHandler {
+	return &ZoneHandler{zones: zones, validate: newValidator(zones)}
+}
+
+// ListZones devuelve las zonas válidas
+func (h *ZoneHandler) ListZones(w http.ResponseWriter, r *http.Request) {
+	zones, err := h.zones.List(r.Context())
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(zones)
+}
+
+// CreateZone añade una zona al catálogo
+func (h *ZoneHandler) CreateZone(w http.ResponseWriter, r *http.Request) {
+	var input ZoneInput
+	if prob := decodeInput(r, &input); prob != nil {
+		problem.Write(w, r, prob)
+		return
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if prob := validateWith(r.Context(), h.validate, &input, nil); prob != nil {
+		problem.Write(w, r, prob)
+		return
+	}
+
+	zone := models.Zone{Name: input.Name}
+	if err := h.zones.Create(r.Context(), &zone); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			problem.Write(w, r, problem.New(problem.CodeZoneExists).WithFields(map[string]string{"name": problem.FieldConflict}))
+			return
+		}
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(zone)
+}
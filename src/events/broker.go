@@ -0,0 +1,148 @@
+// Package events reparte los cambios de usuarios a quien los escucha (el
+// panel de administración por SSE o WebSocket) y guarda los últimos para
+// que un cliente que se reconecta recupere los que se perdió.
+package events
+
+import (
+	"api3/src/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tipos de evento
+const (
+	UserCreated = "user.created"
+	UserUpdated = "user.updated"
+	UserDeleted = "user.deleted"
+	// Reset avisa al cliente de que se perdió eventos y debe recargar
+	Reset = "reset"
+)
+
+// Event es un cambio de usuario. Los ID crecen siempre, también entre
+// reinicios, y sirven de Last-Event-ID.
+type Event struct {
+	ID   uint64       `json:"id"`
+	Type string       `json:"type"`
+	Time time.Time    `json:"time"`
+	User *models.User `json:"user,omitempty"` // estado tras el cambio (el último, si se borró)
+	// Zona anterior si el cambio la movió: el usuario sale de esa zona
+	PrevZona string `json:"prevZona,omitempty"`
+}
+
+// Scope es lo que puede ver un suscriptor: un admin todos los cambios, el
+// resto los de su zona. Zona en un admin limita los eventos a esa zona.
+type Scope struct {
+	Admin bool
+	Zona  string
+}
+
+// Allows dice si el evento entra en el alcance
+func (s Scope) Allows(e Event) bool {
+	if s.Admin && s.Zona == "" {
+		return true
+	}
+	return (e.User != nil && strings.EqualFold(e.User.Zona, s.Zona)) || (e.PrevZona != "" && strings.EqualFold(e.PrevZona, s.Zona))
+}
+
+// Broker publica los eventos de este proceso. Con varias instancias cada una
+// solo reparte los cambios que hizo ella.
+type Broker struct {
+	mu      sync.Mutex
+	lastID  uint64
+	history []Event // los últimos, del más antiguo al más nuevo
+	size    int
+	subs    map[*Subscription]bool
+}
+
+// NewBroker guarda los últimos history eventos para reanudar
+func NewBroker(history int) *Broker {
+	// Los ID parten de la hora de arranque: un Last-Event-ID de un proceso
+	// anterior queda por debajo de todos los de este
+	return &Broker{lastID: uint64(time.Now().UnixMicro()), size: history, subs: map[*Subscription]bool{}}
+}
+
+// Publish reparte un cambio del usuario. prevZona es su zona antes de un
+// cambio (vacía si no cambió).
+func (b *Broker) Publish(typ string, user models.User, prevZona string) Event {
+	user.Password = ""
+	if strings.EqualFold(prevZona, user.Zona) {
+		prevZona = ""
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	e := Event{ID: b.lastID, Type: typ, Time: time.Now().UTC(), User: &user, PrevZona: prevZona}
+	if b.size > 0 {
+		if len(b.history) == b.size {
+			b.history = append(b.history[:0], b.history[1:]...)
+		}
+		b.history = append(b.history, e)
+	}
+	for sub := range b.subs {
+		if !sub.scope.Allows(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			// Un cliente que no da abasto se desconecta; al volver reanuda
+			// desde su último evento
+			delete(b.subs, sub)
+			close(sub.ch)
+		}
+	}
+	return e
+}
+
+// Subscription recibe los eventos de su alcance por C hasta Close. C se
+// cierra también si el suscriptor se queda atrás.
+type Subscription struct {
+	C <-chan Event
+	// LastID es el último evento publicado al suscribirse
+	LastID uint64
+
+	ch     chan Event
+	scope  Scope
+	broker *Broker
+}
+
+// Subscribe empieza a recibir eventos. Con lastID > 0 devuelve además los
+// posteriores a ese que siguen guardados; complete es false si alguno ya se
+// descartó (o el ID no es de este proceso): el cliente debe recargar y
+// seguir desde LastID.
+func (b *Broker) Subscribe(scope Scope, lastID uint64, buffer int) (sub *Subscription, missed []Event, complete bool) {
+	ch := make(chan Event, buffer)
+	sub = &Subscription{C: ch, ch: ch, scope: scope, broker: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[sub] = true
+	sub.LastID = b.lastID
+	if lastID == 0 {
+		return sub, nil, true
+	}
+	// Sin hueco: o no hubo nada después o el más antiguo guardado es el siguiente
+	complete = lastID == b.lastID || (lastID < b.lastID && len(b.history) > 0 && b.history[0].ID <= lastID+1)
+	if !complete {
+		return sub, nil, false
+	}
+	for _, e := range b.history {
+		if e.ID > lastID && scope.Allows(e) {
+			missed = append(missed, e)
+		}
+	}
+	return sub, missed, complete
+}
+
+// Close deja de recibir eventos
+func (s *Subscription) Close() {
+	b := s.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs[s] {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
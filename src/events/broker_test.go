@@ -0,0 +1,67 @@
+package events
+
+import (
+	"api3/src/models"
+	"testing"
+)
+
+func TestScope(t *testing.T) {
+	moved := Event{User: &models.User{Zona: "sur"}, PrevZona: "norte"}
+	tests := []struct {
+		name  string
+		scope Scope
+		want  bool
+	}{
+		{"admin", Scope{Admin: true}, true},
+		{"admin con zona", Scope{Admin: true, Zona: "este"}, false},
+		{"zona nueva", Scope{Zona: "sur"}, true},
+		{"zona anterior", Scope{Zona: "Norte"}, true},
+		{"otra zona", Scope{Zona: "este"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.scope.Allows(moved); got != tt.want {
+			t.Errorf("%s: Allows = %v, se esperaba %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSubscribeResume(t *testing.T) {
+	b := NewBroker(2)
+	first := b.Publish(UserCreated, models.User{ID: 1, Zona: "norte"}, "")
+	b.Publish(UserCreated, models.User{ID: 2, Zona: "sur"}, "")
+	third := b.Publish(UserUpdated, models.User{ID: 1, Zona: "norte"}, "")
+
+	// Los posteriores a first siguen guardados: se recupera lo de su zona
+	sub, missed, complete := b.Subscribe(Scope{Zona: "norte"}, first.ID, 4)
+	if !complete || len(missed) != 1 || missed[0].ID != third.ID {
+		t.Fatalf("reanudar tras %d: complete=%v missed=%v", first.ID, complete, missed)
+	}
+	sub.Close()
+
+	// Antes de first ya no queda historial: hay que recargar
+	if _, missed, complete := b.Subscribe(Scope{Admin: true}, first.ID-1, 4); complete || missed != nil {
+		t.Errorf("reanudar con hueco: complete=%v missed=%v", complete, missed)
+	}
+	// Un ID de un proceso anterior tampoco sirve
+	if _, _, complete := b.Subscribe(Scope{Admin: true}, 1, 4); complete {
+		t.Error("reanudar con un ID antiguo debería pedir recargar")
+	}
+	if _, missed, complete := b.Subscribe(Scope{Admin: true}, third.ID, 4); !complete || len(missed) != 0 {
+		t.Errorf("reanudar al día: complete=%v missed=%v", complete, missed)
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	b := NewBroker(0)
+	sub, _, _ := b.Subscribe(Scope{Admin: true}, 0, 1)
+	b.Publish(UserCreated, models.User{ID: 1}, "")
+	b.Publish(UserCreated, models.User{ID: 2}, "")
+
+	if e := <-sub.C; e.User.ID != 1 || e.User.Password != "" {
+		t.Fatalf("primer evento %+v", e)
+	}
+	if _, ok := <-sub.C; ok {
+		t.Fatal("el suscriptor lento debería quedar desconectado")
+	}
+	sub.Close() // no falla tras la desconexión
+}
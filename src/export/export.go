@@ -0,0 +1,82 @@
+// Package export escribe tablas como CSV, JSON Lines o XLSX fila a fila,
+// sin guardar el resultado completo en memoria
+package export
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Formatos soportados
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+	FormatXLSX  = "xlsx"
+)
+
+// ErrUnknownFormat se devuelve al pedir un formato no soportado
+var ErrUnknownFormat = errors.New("formato de exportación desconocido")
+
+// Writer escribe una tabla: primero la cabecera y luego las filas. Flush
+// envía lo acumulado; Close termina el archivo (y no cierra el io.Writer).
+type Writer interface {
+	WriteHeader(columns []string) error
+	WriteRow(values []interface{}) error
+	Flush() error
+	Close() error
+}
+
+// Formats devuelve los formatos soportados
+func Formats() []string {
+	return []string{FormatCSV, FormatJSONL, FormatXLSX}
+}
+
+// New crea el Writer del formato pedido sobre w
+func New(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatJSONL:
+		return newJSONLWriter(w), nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// ContentType devuelve el tipo MIME de un formato
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSONL:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "application/octet-stream"
+}
+
+// Texto de un valor para los formatos que solo tienen texto
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.UTC().Format(time.RFC3339)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
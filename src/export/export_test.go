@@ -0,0 +1,108 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTable(t *testing.T, format string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := New(format, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteHeader([]string{"id", "username", "updatedAt"}); err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]interface{}{
+		{1, "ana", time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{int64(2), "=SUM(A1)", time.Time{}},
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSV(t *testing.T) {
+	got := string(writeTable(t, FormatCSV))
+	want := "id,username,updatedAt\n1,ana,2026-01-02T03:04:05Z\n2,'=SUM(A1),\n"
+	if got != want {
+		t.Errorf("CSV:\n%s\nquería:\n%s", got, want)
+	}
+}
+
+func TestJSONL(t *testing.T) {
+	got := string(writeTable(t, FormatJSONL))
+	want := `{"id":1,"username":"ana","updatedAt":"2026-01-02T03:04:05Z"}` + "\n" +
+		`{"id":2,"username":"=SUM(A1)","updatedAt":""}` + "\n"
+	if got != want {
+		t.Errorf("JSON Lines:\n%s\nquería:\n%s", got, want)
+	}
+}
+
+// El libro debe ser un zip con todas las partes y una hoja XML válida
+func TestXLSX(t *testing.T) {
+	data := writeTable(t, FormatXLSX)
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if files[name] == nil {
+			t.Fatalf("falta %s", name)
+		}
+	}
+
+	rc, err := files["xl/worksheets/sheet1.xml"].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	sheet, _ := io.ReadAll(rc)
+	var parsed struct {
+		Rows []struct {
+			Cells []struct {
+				Ref   string `xml:"r,attr"`
+				Value string `xml:"v"`
+				Text  string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(sheet, &parsed); err != nil {
+		t.Fatalf("hoja inválida: %v", err)
+	}
+	if len(parsed.Rows) != 3 {
+		t.Fatalf("filas = %d, quería 3", len(parsed.Rows))
+	}
+	last := parsed.Rows[2].Cells
+	if last[0].Ref != "A3" || last[0].Value != "2" || last[1].Text != "=SUM(A1)" {
+		t.Errorf("última fila = %+v", last)
+	}
+	if !strings.Contains(string(sheet), `t="inlineStr"`) {
+		t.Error("los textos deben ir como inlineStr")
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(i); got != want {
+			t.Errorf("columnName(%d) = %s, quería %s", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
+
+// jsonlWriter escribe un objeto por fila con las columnas en el orden de la
+// cabecera
+type jsonlWriter struct {
+	w       *bufio.Writer
+	columns [][]byte
+}
+
+func newJSONLWriter(w io.Writer) *jsonlWriter {
+	return &jsonlWriter{w: bufio.NewWriter(w)}
+}
+
+func (j *jsonlWriter) WriteHeader(columns []string) error {
+	j.columns = make([][]byte, len(columns))
+	for i, name := range columns {
+		key, err := json.Marshal(name)
+		if err != nil {
+			return err
+		}
+		j.columns[i] = key
+	}
+	return nil
+}
+
+func (j *jsonlWriter) WriteRow(values []interface{}) error {
+	if len(values) != len(j.columns) {
+		return errors.New("la fila no coincide con la cabecera")
+	}
+	j.w.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			j.w.WriteByte(',')
+		}
+		if t, ok := v.(time.Time); ok {
+			// Mismo formato que en CSV y XLSX
+			v = formatValue(t)
+		}
+		value, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		j.w.Write(j.columns[i])
+		j.w.WriteByte(':')
+		j.w.Write(value)
+	}
+	j.w.WriteString("}\n")
+	return nil
+}
+
+func (j *jsonlWriter) Flush() error {
+	return j.w.Flush()
+}
+
+func (j *jsonlWriter) Close() error {
+	return j.w.Flush()
+}
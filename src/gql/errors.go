@@ -0,0 +1,62 @@
+package gql
+
+import (
+	"api3/src/i18n"
+	"api3/src/problem"
+	"context"
+	"errors"
+	"log"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/location"
+)
+
+// Error convierte el error de un resolver en uno de GraphQL: un
+// *problem.Problem se traduce al idioma de la petición y su código estable
+// va en extensions; cualquier otro error se registra y se oculta como
+// error interno.
+func Error(ctx context.Context, err error) error {
+	var prob *problem.Problem
+	if !errors.As(err, &prob) {
+		prob = problem.Internal(err)
+	}
+	if cause := errors.Unwrap(prob); cause != nil {
+		log.Printf("❌ GraphQL: %v", cause)
+	}
+	prob.Translate(i18n.FromContext(ctx))
+	return problemError{prob}
+}
+
+// problemError muestra un problema con el mismo código que en REST
+type problemError struct {
+	p *problem.Problem
+}
+
+func (e problemError) Error() string {
+	if e.p.Detail != "" {
+		return e.p.Title + ": " + e.p.Detail
+	}
+	return e.p.Title
+}
+
+// Extensions implementa gqlerrors.ExtendedError
+func (e problemError) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{"code": e.p.Code, "status": e.p.Status}
+	if len(e.p.Errors) > 0 {
+		ext["errors"] = e.p.Errors
+	}
+	if e.p.Current != nil {
+		ext["current"] = e.p.Current
+	}
+	return ext
+}
+
+// Error de la petición entera, que no sale de ningún resolver
+func requestError(ctx context.Context, prob *problem.Problem) gqlerrors.FormattedError {
+	err := Error(ctx, prob).(problemError)
+	return gqlerrors.FormattedError{
+		Message:    err.Error(),
+		Locations:  []location.SourceLocation{},
+		Extensions: err.Extensions(),
+	}
+}
@@ -0,0 +1,102 @@
+// Package gql sirve un esquema GraphQL por HTTP con límites de profundidad
+// y de coste
+package gql
+
+import (
+	"api3/src/i18n"
+	"api3/src/problem"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Request es el cuerpo de POST /graphql
+type Request struct {
+	Query         string                 `json:"query" validate:"required"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response es la respuesta de /graphql: data con lo que se pudo resolver y
+// errors con lo que falló
+type Response struct {
+	Data   interface{}                `json:"data"`
+	Errors []gqlerrors.FormattedError `json:"errors,omitempty"`
+}
+
+// Handler ejecuta las operaciones de POST /graphql contra schema. Una
+// consulta mal escrita, inválida o que supera limits se responde con 400
+// sin ejecutar nada; los errores de los resolvers van en errors, con 200 y
+// los datos que sí se resolvieron.
+func Handler(schema graphql.Schema, limits Limits) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			problem.Write(w, r, problem.New(problem.CodeInvalidBody).WithDetail("detail.malformed_json", err.Error()))
+			return
+		}
+		if strings.TrimSpace(req.Query) == "" {
+			problem.Write(w, r, problem.Validation(map[string]string{"query": problem.FieldRequired}))
+			return
+		}
+
+		locale := i18n.FromRequest(r)
+		i18n.SetHeaders(w, locale)
+		ctx := i18n.WithLocale(r.Context(), locale)
+
+		doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+			Body: []byte(req.Query),
+			Name: "GraphQL request",
+		})})
+		if err != nil {
+			writeResponse(w, http.StatusBadRequest, Response{Errors: gqlerrors.FormatErrors(err)})
+			return
+		}
+		if result := graphql.ValidateDocument(&schema, doc, nil); !result.IsValid {
+			writeResponse(w, http.StatusBadRequest, Response{Errors: result.Errors})
+			return
+		}
+		if prob := limits.Check(&schema, doc, req.OperationName, req.Variables); prob != nil {
+			writeResponse(w, http.StatusBadRequest, Response{Errors: []gqlerrors.FormattedError{requestError(ctx, prob)}})
+			return
+		}
+
+		result := graphql.Execute(graphql.ExecuteParams{
+			Schema:        schema,
+			AST:           doc,
+			OperationName: req.OperationName,
+			Args:          req.Variables,
+			Context:       ctx,
+		})
+		status := http.StatusOK
+		if !executed(result) {
+			status = http.StatusBadRequest
+		}
+		writeResponse(w, status, Response{Data: result.Data, Errors: result.Errors})
+	}
+}
+
+// Si la operación no llegó a ejecutarse (variables inválidas, operación
+// desconocida) no hay datos y ningún error es de un campo
+func executed(result *graphql.Result) bool {
+	if result.Data != nil {
+		return true
+	}
+	for _, err := range result.Errors {
+		if len(err.Path) > 0 {
+			return true
+		}
+	}
+	return !result.HasErrors()
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,203 @@
+package gql
+
+import (
+	"api3/src/problem"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Argumento que multiplica el coste de lo que hay debajo de un campo
+const limitArg = "limit"
+
+// Tope del coste calculado, para no desbordar con límites enormes
+const maxCost = 1 << 40
+
+// Limits acota las consultas antes de ejecutarlas. MaxDepth es el máximo
+// anidamiento de campos; MaxComplexity, el coste máximo: cada campo cuesta
+// 1 y un campo con argumento limit multiplica por él el coste de sus
+// hijos. Cero desactiva la comprobación.
+type Limits struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// Check mide la operación que se va a ejecutar. doc ya debe estar validado
+// (sin ciclos de fragmentos ni campos desconocidos); la introspección
+// (__schema, __type, __typename) no cuenta.
+func (l Limits) Check(schema *graphql.Schema, doc *ast.Document, operationName string, variables map[string]interface{}) *problem.Problem {
+	m := measurer{schema: schema, variables: variables, fragments: map[string]*ast.FragmentDefinition{}}
+	var op *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.FragmentDefinition:
+			m.fragments[def.Name.Value] = def
+		case *ast.OperationDefinition:
+			if operationName == "" || (def.Name != nil && def.Name.Value == operationName) {
+				op = def
+			}
+		}
+	}
+	if op == nil {
+		return nil // la ejecución dará el error de operación desconocida
+	}
+	m.defaults = map[string]ast.Value{}
+	for _, v := range op.VariableDefinitions {
+		if v.DefaultValue != nil {
+			m.defaults[v.Variable.Name.Value] = v.DefaultValue
+		}
+	}
+
+	var root *graphql.Object
+	switch op.Operation {
+	case ast.OperationTypeMutation:
+		root = schema.MutationType()
+	case ast.OperationTypeSubscription:
+		root = schema.SubscriptionType()
+	default:
+		root = schema.QueryType()
+	}
+	if root == nil {
+		return nil
+	}
+
+	depth, cost := m.selection(root, op.SelectionSet)
+	if l.MaxDepth > 0 && depth > l.MaxDepth {
+		return problem.New(problem.CodeQueryTooDeep).WithDetail("detail.query_depth", depth, l.MaxDepth)
+	}
+	if l.MaxComplexity > 0 && cost > l.MaxComplexity {
+		return problem.New(problem.CodeQueryTooComplex).WithDetail("detail.query_complexity", cost, l.MaxComplexity)
+	}
+	return nil
+}
+
+type measurer struct {
+	schema    *graphql.Schema
+	variables map[string]interface{}
+	defaults  map[string]ast.Value
+	fragments map[string]*ast.FragmentDefinition
+}
+
+// selection devuelve la profundidad y el coste de un conjunto de campos de
+// parent
+func (m *measurer) selection(parent graphql.Type, set *ast.SelectionSet) (depth, cost int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, sel := range set.Selections {
+		var d, c int
+		switch sel := sel.(type) {
+		case *ast.Field:
+			d, c = m.field(parent, sel)
+		case *ast.InlineFragment:
+			d, c = m.selection(m.condition(parent, sel.TypeCondition), sel.SelectionSet)
+		case *ast.FragmentSpread:
+			if frag, ok := m.fragments[sel.Name.Value]; ok {
+				d, c = m.selection(m.condition(parent, frag.TypeCondition), frag.SelectionSet)
+			}
+		}
+		if d > depth {
+			depth = d
+		}
+		cost = saturate(cost + c)
+	}
+	return depth, cost
+}
+
+func (m *measurer) field(parent graphql.Type, f *ast.Field) (depth, cost int) {
+	if strings.HasPrefix(f.Name.Value, "__") {
+		return 0, 0
+	}
+	var def *graphql.FieldDefinition
+	if fields, ok := parent.(interface {
+		Fields() graphql.FieldDefinitionMap
+	}); ok {
+		def = fields.Fields()[f.Name.Value]
+	}
+	if def == nil {
+		return 1, 1
+	}
+	child, _ := graphql.GetNamed(def.Type).(graphql.Type)
+	depth, cost = m.selection(child, f.SelectionSet)
+	return depth + 1, saturate(1 + multiply(m.multiplier(def, f), cost))
+}
+
+// Valor efectivo del argumento limit: el de la consulta, el de su variable
+// (o el valor por defecto de esta) o el del esquema
+func (m *measurer) multiplier(def *graphql.FieldDefinition, f *ast.Field) int {
+	var schemaDefault interface{}
+	found := false
+	for _, arg := range def.Args {
+		if arg.Name() == limitArg {
+			schemaDefault, found = arg.DefaultValue, true
+		}
+	}
+	if !found {
+		return 1
+	}
+
+	n := 0
+	for _, arg := range f.Arguments {
+		if arg.Name.Value == limitArg {
+			n = m.intValue(arg.Value)
+		}
+	}
+	if n == 0 {
+		if v, ok := schemaDefault.(int); ok {
+			n = v
+		}
+	}
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+func (m *measurer) intValue(v ast.Value) int {
+	switch v := v.(type) {
+	case *ast.IntValue:
+		n, _ := strconv.Atoi(v.Value)
+		return n
+	case *ast.Variable:
+		switch x := m.variables[v.Name.Value].(type) {
+		case float64: // variables decodificadas de JSON
+			if x > maxCost {
+				return maxCost
+			}
+			return int(x)
+		case int:
+			return x
+		}
+		if def, ok := m.defaults[v.Name.Value]; ok {
+			return m.intValue(def)
+		}
+	}
+	return 0
+}
+
+// Tipo sobre el que se aplica un fragmento
+func (m *measurer) condition(parent graphql.Type, cond *ast.Named) graphql.Type {
+	if cond == nil {
+		return parent
+	}
+	if t := m.schema.Type(cond.Name.Value); t != nil {
+		return t
+	}
+	return parent
+}
+
+func multiply(a, b int) int {
+	if b > 0 && a > maxCost/b {
+		return maxCost
+	}
+	return a * b
+}
+
+func saturate(n int) int {
+	if n > maxCost || n < 0 {
+		return maxCost
+	}
+	return n
+}
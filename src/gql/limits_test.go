@@ -0,0 +1,65 @@
+package gql
+
+import (
+	"api3/src/problem"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+func testSchema(t *testing.T) graphql.Schema {
+	t.Helper()
+	item := graphql.NewObject(graphql.ObjectConfig{Name: "Item", Fields: graphql.Fields{
+		"name": &graphql.Field{Type: graphql.String},
+	}})
+	item.AddFieldConfig("children", &graphql.Field{
+		Type: graphql.NewList(item),
+		Args: graphql.FieldConfigArgument{"limit": {Type: graphql.Int}},
+	})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: graphql.Fields{
+		"items": &graphql.Field{
+			Type: graphql.NewList(item),
+			Args: graphql.FieldConfigArgument{"limit": {Type: graphql.Int, DefaultValue: 10}},
+		},
+	}})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func TestLimitsCheck(t *testing.T) {
+	schema := testSchema(t)
+	limits := Limits{MaxDepth: 3, MaxComplexity: 100}
+	tests := []struct {
+		name  string
+		query string
+		vars  map[string]interface{}
+		code  string
+	}{
+		{"límite por defecto del esquema", `{ items { name } }`, nil, ""},                                                 // 1 + 10·1
+		{"límite literal", `{ items(limit: 99) { name } }`, nil, ""},                                                      // 1 + 99·1
+		{"coste excesivo", `{ items(limit: 50) { name children(limit: 5) { name } } }`, nil, problem.CodeQueryTooComplex}, // 1 + 50·(1 + 1 + 5)
+		{"límite en variable", `query($n: Int) { items(limit: $n) { name } }`, map[string]interface{}{"n": float64(200)}, problem.CodeQueryTooComplex},
+		{"valor por defecto de la variable", `query($n: Int = 200) { items(limit: $n) { name } }`, nil, problem.CodeQueryTooComplex},
+		{"fragmentos", `{ items(limit: 1) { ...f } } fragment f on Item { children { children { name } } }`, nil, problem.CodeQueryTooDeep},
+		{"introspección", `{ __schema { types { fields { type { name } } } } items(limit: 1) { __typename } }`, nil, ""},
+	}
+	for _, tt := range tests {
+		doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if result := graphql.ValidateDocument(&schema, doc, nil); !result.IsValid {
+			t.Fatalf("%s: consulta inválida: %v", tt.name, result.Errors)
+		}
+		code := ""
+		if prob := limits.Check(&schema, doc, "", tt.vars); prob != nil {
+			code = prob.Code
+		}
+		if code != tt.code {
+			t.Errorf("%s: código %q, se esperaba %q", tt.name, code, tt.code)
+		}
+	}
+}
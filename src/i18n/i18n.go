@@ -0,0 +1,142 @@
+// Package i18n traduce los mensajes de la API según Accept-Language
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+)
+
+// Idioma por defecto si DEFAULT_LOCALE no indica otro soportado
+const FallbackLocale = "es"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// Catálogos por idioma: clave → mensaje (con verbos de fmt si lleva datos)
+var catalogs = loadCatalogs()
+
+var (
+	matcherOnce sync.Once
+	matcher     language.Matcher
+	matchTags   []string
+)
+
+func loadCatalogs() map[string]map[string]string {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	result := map[string]map[string]string{}
+	for _, e := range entries {
+		data, err := localeFiles.ReadFile(path.Join("locales", e.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var catalog map[string]string
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			panic(fmt.Sprintf("catálogo %s inválido: %v", e.Name(), err))
+		}
+		result[strings.TrimSuffix(e.Name(), ".json")] = catalog
+	}
+	return result
+}
+
+// Locales devuelve los idiomas con catálogo
+func Locales() []string {
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	return locales
+}
+
+// DefaultLocale es el idioma cuando el cliente no pide uno soportado.
+// Se lee DEFAULT_LOCALE en cada llamada porque el .env se carga después
+// de inicializar los paquetes.
+func DefaultLocale() string {
+	if locale := strings.ToLower(os.Getenv("DEFAULT_LOCALE")); catalogs[locale] != nil {
+		return locale
+	}
+	return FallbackLocale
+}
+
+// Negotiate elige el idioma a partir de una cabecera Accept-Language
+func Negotiate(acceptLanguage string) string {
+	if acceptLanguage == "" {
+		return DefaultLocale()
+	}
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale()
+	}
+
+	matcherOnce.Do(func() {
+		// El primero es el que devuelve el matcher si nada encaja
+		matchTags = append([]string{DefaultLocale()}, Locales()...)
+		supported := make([]language.Tag, len(matchTags))
+		for i, locale := range matchTags {
+			supported[i] = language.Make(locale)
+		}
+		matcher = language.NewMatcher(supported)
+	})
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return DefaultLocale()
+	}
+	return matchTags[index]
+}
+
+// FromRequest devuelve el idioma pedido en la petición
+func FromRequest(r *http.Request) string {
+	return Negotiate(r.Header.Get("Accept-Language"))
+}
+
+type localeKey struct{}
+
+// WithLocale guarda en ctx el idioma de la petición, para las capas que no
+// reciben el *http.Request (resolvers de GraphQL, gRPC)
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// FromContext devuelve el idioma guardado con WithLocale o el de por defecto
+func FromContext(ctx context.Context) string {
+	if locale, ok := ctx.Value(localeKey{}).(string); ok {
+		return locale
+	}
+	return DefaultLocale()
+}
+
+// T traduce key al idioma dado; si falta usa el idioma por defecto y, como
+// último recurso, devuelve la clave
+func T(locale, key string, args ...interface{}) string {
+	msg, ok := catalogs[locale][key]
+	if !ok {
+		msg, ok = catalogs[DefaultLocale()][key]
+	}
+	if !ok {
+		msg, ok = catalogs[FallbackLocale][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// SetHeaders indica el idioma de la respuesta y que depende de Accept-Language
+func SetHeaders(w http.ResponseWriter, locale string) {
+	w.Header().Set("Content-Language", locale)
+	w.Header().Add("Vary", "Accept-Language")
+}
@@ -0,0 +1,76 @@
+package i18n
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// Cada clave de cualquier catálogo debe estar traducida en todos los idiomas
+func TestEveryKeyTranslated(t *testing.T) {
+	if len(catalogs) < 2 {
+		t.Fatalf("se esperaban al menos dos idiomas, hay %v", Locales())
+	}
+	keys := map[string]bool{}
+	for _, catalog := range catalogs {
+		for key := range catalog {
+			keys[key] = true
+		}
+	}
+	for locale, catalog := range catalogs {
+		for key := range keys {
+			if catalog[key] == "" {
+				t.Errorf("%s: falta la traducción de %q", locale, key)
+			}
+		}
+	}
+}
+
+var verbPattern = regexp.MustCompile(`%[-+# 0]*[0-9]*[a-zA-Z%]`)
+
+// Las traducciones deben recibir los mismos datos en el mismo orden
+func TestPlaceholdersMatch(t *testing.T) {
+	reference := catalogs[FallbackLocale]
+	for locale, catalog := range catalogs {
+		for key, msg := range catalog {
+			want := verbPattern.FindAllString(reference[key], -1)
+			got := verbPattern.FindAllString(msg, -1)
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("%s: %q usa %v y %s usa %v", locale, key, got, FallbackLocale, want)
+			}
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "es"},
+		{"en", "en"},
+		{"en-US,en;q=0.9,es;q=0.8", "en"},
+		{"es-MX", "es"},
+		{"fr-FR", "es"},
+		{"fr;q=1, en;q=0.5", "en"},
+		{"es;q=0.2, en;q=0.9", "en"},
+		{"no es una cabecera válida;;", "es"},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, se esperaba %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestTFallback(t *testing.T) {
+	if got := T("en", "detail.limit_range", 200); got != "limit must be between 1 and 200" {
+		t.Errorf("T con datos = %q", got)
+	}
+	if got := T("fr", "problem.user_not_found"); got != "Usuario no encontrado" {
+		t.Errorf("un idioma sin catálogo debe usar el de por defecto, se obtuvo %q", got)
+	}
+	if got := T("en", "clave.inexistente"); got != "clave.inexistente" {
+		t.Errorf("una clave desconocida debe devolverse tal cual, se obtuvo %q", got)
+	}
+}
@@ -0,0 +1,147 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // registra el decodificador GIF
+	"image/jpeg"
+	"image/png"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// Límites de las imágenes subidas
+const (
+	MaxBytes     = 5 << 20    // tamaño máximo del archivo
+	MaxDimension = 4096       // ancho o alto máximo en píxeles
+	MaxPixels    = 16_000_000 // ancho*alto máximo; frena las "bombas de descompresión"
+)
+
+// Tamaños derivados que se generan al subir una imagen
+const (
+	SizeOriginal = "original"
+	SizeSmall    = "64"
+	SizeMedium   = "256"
+)
+
+var variantPixels = map[string]int{SizeSmall: 64, SizeMedium: 256}
+
+// Formatos aceptados, con el nombre que devuelve image.DecodeConfig
+var allowedFormats = map[string]bool{"png": true, "jpeg": true, "gif": true}
+
+var (
+	ErrTooLarge          = errors.New("la imagen supera el tamaño máximo permitido")
+	ErrTooManyPixels     = errors.New("la imagen supera las dimensiones máximas permitidas")
+	ErrUnsupportedFormat = errors.New("formato de imagen no permitido (usa PNG, JPEG o GIF)")
+	ErrInvalidImage      = errors.New("la imagen está dañada o no es una imagen")
+)
+
+// Variant es una versión codificada de la imagen lista para guardar
+type Variant struct {
+	Size        string
+	Data        []byte
+	ContentType string
+}
+
+// Process valida la imagen subida y genera el original limpio y las
+// miniaturas. Al volver a codificar se descartan los metadatos (EXIF, GPS).
+func Process(data []byte) ([]Variant, error) {
+	if len(data) > MaxBytes {
+		return nil, ErrTooLarge
+	}
+
+	// Se leen solo las dimensiones antes de decodificar para no reservar
+	// memoria para una imagen gigante
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil, ErrUnsupportedFormat
+		}
+		return nil, ErrInvalidImage
+	}
+	if !allowedFormats[format] {
+		return nil, ErrUnsupportedFormat
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > MaxDimension || cfg.Height > MaxDimension ||
+		cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if format == "jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+
+	original, contentType, err := encode(img, format)
+	if err != nil {
+		return nil, err
+	}
+	variants := []Variant{{Size: SizeOriginal, Data: original, ContentType: contentType}}
+
+	for _, size := range []string{SizeSmall, SizeMedium} {
+		thumb, contentType, err := encode(thumbnail(img, variantPixels[size]), format)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, Variant{Size: size, Data: thumb, ContentType: contentType})
+	}
+	return variants, nil
+}
+
+// ValidSize indica si size es uno de los tamaños que se generan
+func ValidSize(size string) bool {
+	_, ok := variantPixels[size]
+	return ok || size == SizeOriginal
+}
+
+// VariantKey devuelve la clave del BlobStore para un tamaño derivado
+func VariantKey(key, size string) string {
+	if size == "" || size == SizeOriginal {
+		return key
+	}
+	return key + "_" + size
+}
+
+// VariantKeys devuelve todas las claves que genera una subida
+func VariantKeys(key string) []string {
+	return []string{key, VariantKey(key, SizeSmall), VariantKey(key, SizeMedium)}
+}
+
+// JPEG se mantiene como JPEG; PNG y GIF se guardan como PNG (un GIF animado
+// queda con su primer fotograma)
+func encode(img image.Image, format string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+			return nil, "", fmt.Errorf("no se pudo codificar la imagen: %w", err)
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", fmt.Errorf("no se pudo codificar la imagen: %w", err)
+	}
+	return buf.Bytes(), "image/png", nil
+}
+
+// Recorta el centro en un cuadrado y lo escala a size px sin agrandar
+func thumbnail(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2))
+
+	if side < size {
+		size = side
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Src, nil)
+	return dst
+}
@@ -0,0 +1,82 @@
+package images
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"os"
+	"testing"
+)
+
+// Las fotos de testdata miden 320x160 con la mitad izquierda roja y la
+// derecha azul; solo cambia la etiqueta EXIF Orientation
+func TestProcessOrientation(t *testing.T) {
+	for _, tt := range []struct {
+		file        string
+		orientation int
+		width       int
+		height      int
+		redAt       image.Point // un punto que debe quedar rojo
+	}{
+		{"testdata/orientation-1.jpg", 1, 320, 160, image.Pt(10, 80)},
+		{"testdata/orientation-6.jpg", 6, 160, 320, image.Pt(80, 10)},  // rojo arriba
+		{"testdata/orientation-8.jpg", 8, 160, 320, image.Pt(80, 310)}, // rojo abajo
+	} {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := jpegOrientation(data); got != tt.orientation {
+				t.Fatalf("orientación %d, se esperaba %d", got, tt.orientation)
+			}
+
+			variants, err := Process(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]image.Point{
+				SizeOriginal: image.Pt(tt.width, tt.height),
+				SizeSmall:    image.Pt(64, 64),
+				SizeMedium:   image.Pt(160, 160), // sin agrandar: el lado corto mide 160
+			}
+			for _, v := range variants {
+				if v.ContentType != "image/jpeg" {
+					t.Errorf("%s: Content-Type %s", v.Size, v.ContentType)
+				}
+				img, err := jpeg.Decode(bytes.NewReader(v.Data))
+				if err != nil {
+					t.Fatalf("%s: %v", v.Size, err)
+				}
+				if got := img.Bounds().Size(); got != want[v.Size] {
+					t.Errorf("%s mide %v, se esperaba %v", v.Size, got, want[v.Size])
+				}
+				if v.Size != SizeOriginal {
+					continue
+				}
+				if r, _, b, _ := img.At(tt.redAt.X, tt.redAt.Y).RGBA(); r < b {
+					t.Errorf("el punto %v no es rojo: la rotación va al revés", tt.redAt)
+				}
+				if jpegOrientation(v.Data) != 1 {
+					t.Error("el original conserva la etiqueta EXIF")
+				}
+			}
+		})
+	}
+}
+
+func TestProcessRejects(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"no es imagen", []byte("hola"), ErrUnsupportedFormat},
+		{"JPEG truncado", []byte{0xFF, 0xD8, 0xFF}, ErrInvalidImage},
+		{"demasiado grande", make([]byte, MaxBytes+1), ErrTooLarge},
+	} {
+		if _, err := Process(tt.data); err != tt.err {
+			t.Errorf("%s: %v, se esperaba %v", tt.name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package images
+
+import (
+	"api3/src/models"
+	"api3/src/repository"
+	"api3/src/storage"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Prefijo de las claves direccionadas por contenido (images/<sha256>).
+// Las claves anteriores (users/<aleatorio>) no se comparten y se borran
+// directamente.
+const contentKeyPrefix = "images/"
+
+// Store guarda las imágenes de perfil una sola vez por contenido y lleva la
+// cuenta de cuántos usuarios usan cada una
+type Store struct {
+	blobs storage.BlobStore
+	refs  repository.ImageRepository
+}
+
+func NewStore(blobs storage.BlobStore, refs repository.ImageRepository) *Store {
+	return &Store{blobs: blobs, refs: refs}
+}
+
+// Save valida y procesa la imagen, la guarda si su contenido no existía y
+// suma una referencia. Devuelve la clave del original.
+func (s *Store) Save(ctx context.Context, data []byte) (string, error) {
+	variants, err := Process(data)
+	if err != nil {
+		return "", err
+	}
+	return s.save(ctx, variants)
+}
+
+// SaveRaw guarda los bytes tal cual, sin validar ni generar miniaturas
+// (para imágenes antiguas que no pasan la validación)
+func (s *Store) SaveRaw(ctx context.Context, data []byte) (string, error) {
+	return s.save(ctx, []Variant{{Size: SizeOriginal, Data: data, ContentType: http.DetectContentType(data)}})
+}
+
+func (s *Store) save(ctx context.Context, variants []Variant) (string, error) {
+	original := variants[0]
+	sum := sha256.Sum256(original.Data)
+	hash := hex.EncodeToString(sum[:])
+	key := contentKeyPrefix + hash
+
+	created, err := s.refs.Acquire(ctx, &models.Image{
+		Hash:        hash,
+		ContentType: original.ContentType,
+		Size:        int64(len(original.Data)),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Si ya existía solo se vuelve a subir cuando falta el original (una
+	// subida anterior que falló a medias)
+	if !created {
+		rc, err := s.blobs.Get(ctx, key)
+		if err == nil {
+			rc.Close()
+			return key, nil
+		}
+		if !errors.Is(err, storage.ErrBlobNotFound) {
+			s.refs.Release(ctx, hash)
+			return "", err
+		}
+	}
+
+	for _, v := range variants {
+		if err := s.blobs.Put(ctx, VariantKey(key, v.Size), bytes.NewReader(v.Data), v.ContentType); err != nil {
+			s.refs.Release(ctx, hash)
+			return "", err
+		}
+	}
+	return key, nil
+}
+
+// Release quita una referencia a la imagen. Las imágenes compartidas las
+// borra el recolector cuando nadie las usa.
+func (s *Store) Release(ctx context.Context, key string) error {
+	if key == "" {
+		return nil
+	}
+	if hash, ok := strings.CutPrefix(key, contentKeyPrefix); ok {
+		return s.refs.Release(ctx, hash)
+	}
+
+	var firstErr error
+	for _, k := range VariantKeys(key) {
+		if err := s.blobs.Delete(ctx, k); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Open abre el tamaño pedido de la imagen. Si no existe (imágenes
+// anteriores a las miniaturas) devuelve el original; el segundo valor
+// indica el tamaño que realmente se abrió.
+func (s *Store) Open(ctx context.Context, key, size string) (io.ReadCloser, string, error) {
+	if size == "" {
+		size = SizeOriginal
+	}
+	rc, err := s.blobs.Get(ctx, VariantKey(key, size))
+	if errors.Is(err, storage.ErrBlobNotFound) && size != SizeOriginal {
+		size = SizeOriginal
+		rc, err = s.blobs.Get(ctx, key)
+	}
+	return rc, size, err
+}
+
+// CollectGarbage borra los blobs de las imágenes que llevan sin referencias
+// más de grace. El margen evita borrar una imagen que se acaba de liberar y
+// se va a volver a subir.
+func (s *Store) CollectGarbage(ctx context.Context, grace time.Duration) (int, error) {
+	removed := 0
+	for {
+		hashes, err := s.refs.ClaimOrphans(ctx, time.Now().UTC().Add(-grace), 100)
+		if err != nil {
+			return removed, err
+		}
+		if len(hashes) == 0 {
+			return removed, nil
+		}
+
+		for _, hash := range hashes {
+			if err := s.deleteBlobs(ctx, contentKeyPrefix+hash); err != nil {
+				// Queda marcada; se reintenta en la próxima pasada
+				log.Printf("⚠️  GC: no se pudo borrar la imagen %s: %v", hash, err)
+				continue
+			}
+			if err := s.refs.Forget(ctx, hash); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+	}
+}
+
+// StartGC ejecuta CollectGarbage cada interval hasta que se cancele ctx
+func (s *Store) StartGC(ctx context.Context, interval, grace time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				removed, err := s.CollectGarbage(ctx, grace)
+				if err != nil {
+					log.Println("⚠️  GC de imágenes:", err)
+				}
+				if removed > 0 {
+					log.Printf("🧹 GC de imágenes: %d imágenes huérfanas eliminadas", removed)
+				}
+			}
+		}
+	}()
+}
+
+func (s *Store) deleteBlobs(ctx context.Context, key string) error {
+	for _, k := range VariantKeys(key) {
+		if err := s.blobs.Delete(ctx, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
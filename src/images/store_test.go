@@ -0,0 +1,164 @@
+package images
+
+import (
+	"api3/src/models"
+	"api3/src/repository"
+	"api3/src/storage"
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+)
+
+// Con un margen negativo el recolector no espera: cualquier imagen sin
+// referencias es huérfana
+const noGrace = -time.Second
+
+func testPNG(t *testing.T, c color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newTestStore(t *testing.T) (*Store, storage.BlobStore, *repository.MemoryImageRepository) {
+	t.Helper()
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := repository.NewMemoryImageRepository()
+	return NewStore(blobs, refs), blobs, refs
+}
+
+// stored indica si están todos los blobs de la imagen (o ninguno)
+func stored(t *testing.T, blobs storage.BlobStore, key string) bool {
+	t.Helper()
+	found := 0
+	for _, k := range VariantKeys(key) {
+		rc, err := blobs.Get(context.Background(), k)
+		if err == nil {
+			rc.Close()
+			found++
+		} else if !errors.Is(err, storage.ErrBlobNotFound) {
+			t.Fatal(err)
+		}
+	}
+	if found != 0 && found != len(VariantKeys(key)) {
+		t.Fatalf("%s tiene %d de %d blobs", key, found, len(VariantKeys(key)))
+	}
+	return found > 0
+}
+
+func collect(t *testing.T, s *Store) int {
+	t.Helper()
+	removed, err := s.CollectGarbage(context.Background(), noGrace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return removed
+}
+
+func TestStoreRefCounting(t *testing.T) {
+	ctx := context.Background()
+	s, blobs, _ := newTestStore(t)
+	red, blue := testPNG(t, color.RGBA{R: 255, A: 255}), testPNG(t, color.RGBA{B: 255, A: 255})
+
+	// ana y bea suben la misma foto: un solo blob con dos referencias
+	anaKey, err := s.Save(ctx, red)
+	if err != nil {
+		t.Fatal(err)
+	}
+	beaKey, err := s.Save(ctx, red)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if anaKey != beaKey {
+		t.Fatalf("la misma foto dio las claves %s y %s", anaKey, beaKey)
+	}
+
+	// ana la reemplaza: queda una referencia y el recolector no la toca
+	newKey, err := s.Save(ctx, blue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Release(ctx, anaKey); err != nil {
+		t.Fatal(err)
+	}
+	if n := collect(t, s); n != 0 || !stored(t, blobs, beaKey) {
+		t.Fatalf("con una referencia el recolector borró %d imágenes", n)
+	}
+
+	// bea borra la suya: llega a 0 y solo esa se borra
+	if err := s.Release(ctx, beaKey); err != nil {
+		t.Fatal(err)
+	}
+	if n := collect(t, s); n != 1 {
+		t.Errorf("el recolector borró %d imágenes, se esperaba 1", n)
+	}
+	if stored(t, blobs, beaKey) {
+		t.Error("la imagen sin referencias sigue guardada")
+	}
+	if !stored(t, blobs, newKey) {
+		t.Error("se borró la imagen nueva de ana")
+	}
+	if n := collect(t, s); n != 0 {
+		t.Errorf("una segunda pasada borró %d imágenes", n)
+	}
+
+	// La misma foto se puede volver a subir después de recolectarla
+	again, err := s.Save(ctx, red)
+	if err != nil || again != beaKey || !stored(t, blobs, again) {
+		t.Errorf("volver a subir: %s %v", again, err)
+	}
+}
+
+// Mientras el recolector borra una imagen (ref_count = -1) no se le pueden
+// sumar referencias
+func TestStoreTombstone(t *testing.T) {
+	ctx := context.Background()
+	s, _, refs := newTestStore(t)
+	red := testPNG(t, color.RGBA{R: 255, A: 255})
+
+	key, err := s.Save(ctx, red)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Release(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	claimed, err := refs.ClaimOrphans(ctx, time.Now().Add(time.Second), 10)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("marcadas %v, %v", claimed, err)
+	}
+
+	if _, err := s.Save(ctx, red); !errors.Is(err, repository.ErrImageBusy) {
+		t.Errorf("subir una imagen marcada: %v, se esperaba ErrImageBusy", err)
+	}
+	// Liberarla de nuevo no deshace la marca
+	if err := s.Release(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := refs.Acquire(ctx, &models.Image{Hash: claimed[0]}); !errors.Is(err, repository.ErrImageBusy) {
+		t.Errorf("tras Release: %v, se esperaba ErrImageBusy", err)
+	}
+
+	if err := refs.Forget(ctx, claimed[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Save(ctx, red); err != nil {
+		t.Errorf("tras olvidarla: %v", err)
+	}
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Image registra una imagen guardada en el BlobStore por su SHA-256 y
+// cuántos usuarios la usan
+type Image struct {
+	Hash        string `gorm:"primaryKey"`
+	RefCount    int
+	ContentType string
+	Size        int64
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
@@ -2,18 +2,57 @@ package models
 
 import (
 	"encoding/base64"
+	"fmt"
 	"net/http"
+	"path"
+	"time"
 )
 
+// Estados de un usuario
+const (
+	StatusActive    = "active"
+	StatusInactive  = "inactive"
+	StatusSuspended = "suspended"
+)
+
+// Roles de un usuario
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// ValidRole indica si role es uno de los roles conocidos
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleUser
+}
+
+// ValidStatus indica si status es uno de los estados conocidos
+func ValidStatus(status string) bool {
+	return status == StatusActive || status == StatusInactive || status == StatusSuspended
+}
+
 type User struct {
 	ID       int    `json:"id"`
 	Username string `json:"username"`
-	Password string `json:"-"`           // omitido al retornar
+	Password string `json:"-"` // omitido al retornar
 	Role     string `json:"role"`
 	Zona     string `json:"zona"`
-	Image    []byte `json:"-"`           // imagen en crudo (no se expone en JSON)
-	ImageStr string `json:"image"`       // imagen codificada base64
-	MimeType string `json:"imageType"`   // tipo MIME (ej: image/png)
+	Status   string `json:"status"`
+	ImageKey string `json:"-"`                            // clave de la imagen en el BlobStore
+	Image    []byte `json:"-" gorm:"-"`                   // imagen en crudo cargada del BlobStore (no se expone en JSON)
+	ImageURL string `json:"imageUrl,omitempty" gorm:"-"`  // URL de GET /users/{id}/image
+	ImageStr string `json:"image,omitempty" gorm:"-"`     // imagen codificada base64 (solo con ?image=inline)
+	MimeType string `json:"imageType,omitempty" gorm:"-"` // tipo MIME (ej: image/png)
+
+	DisplayName string `json:"displayName"` // nombre para mostrar (opcional); entra en la búsqueda
+
+	Version   int64     `json:"version"`   // aumenta con cada cambio (control de concurrencia)
+	UpdatedAt time.Time `json:"updatedAt"` // fecha del último cambio
+}
+
+// ETag identifica la versión del usuario para If-Match / If-None-Match
+func (u *User) ETag() string {
+	return fmt.Sprintf(`"%d"`, u.Version)
 }
 
 // Procesa la imagen para mostrarla en JSON
@@ -23,3 +62,19 @@ func (u *User) FormatImage() {
 		u.ImageStr = base64.StdEncoding.EncodeToString(u.Image)
 	}
 }
+
+// ImageVersion identifica el contenido de la imagen actual: cada subida usa
+// una clave nueva, así que sirve como ETag fuerte
+func (u *User) ImageVersion() string {
+	if u.ImageKey == "" {
+		return ""
+	}
+	return path.Base(u.ImageKey)
+}
+
+// Rellena la URL de la imagen para mostrarla en JSON sin incrustarla
+func (u *User) FormatImageURL() {
+	if u.ImageKey != "" {
+		u.ImageURL = fmt.Sprintf("/api/v1/users/%d/image?v=%s", u.ID, u.ImageVersion())
+	}
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Estados de una entrega de webhook
+const (
+	DeliveryPending   = "pending"   // por enviar o a la espera de reintentar
+	DeliveryDelivered = "delivered" // el receptor respondió 2xx
+	DeliveryDead      = "dead"      // se agotaron los intentos (lista de fallidas)
+)
+
+// Webhook es una suscripción de otro sistema a los cambios de usuarios
+type Webhook struct {
+	ID          int       `json:"id"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"-"`      // firma las entregas; solo se muestra al crearlo
+	Events      EventList `json:"events"` // tipos de evento; vacío: todos
+	Description string    `json:"description"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Wants dice si el webhook recibe los eventos del tipo dado
+func (w *Webhook) Wants(eventType string) bool {
+	if !w.Active {
+		return false
+	}
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery es el envío de un evento a un webhook. Payload es el JSON
+// que se envía, igual en cada intento.
+type WebhookDelivery struct {
+	ID            int64            `json:"id"`
+	WebhookID     int              `json:"webhookId"`
+	EventID       uint64           `json:"eventId"`
+	EventType     string           `json:"eventType"`
+	Payload       string           `json:"payload"`
+	Status        string           `json:"status"`
+	Attempts      int              `json:"attempts"`
+	NextAttemptAt *time.Time       `json:"nextAttemptAt,omitempty"`
+	LastStatus    int              `json:"lastStatus,omitempty"` // código HTTP de la última respuesta
+	LastError     string           `json:"lastError,omitempty"`
+	CreatedAt     time.Time        `json:"createdAt"`
+	UpdatedAt     time.Time        `json:"updatedAt"`
+	Log           []WebhookAttempt `json:"log,omitempty" gorm:"-"` // intentos (solo al consultar una entrega)
+}
+
+// WebhookAttempt registra un intento de entrega
+type WebhookAttempt struct {
+	ID         int64     `json:"-"`
+	DeliveryID int64     `json:"-"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// EventList es una lista de tipos de evento; en la BD va separada por comas
+type EventList []string
+
+func (l EventList) Value() (driver.Value, error) {
+	return strings.Join(l, ","), nil
+}
+
+func (l *EventList) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("no se puede leer %T como lista de eventos", value)
+	}
+	*l = nil
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			*l = append(*l, e)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON escribe [] en vez de null para la lista vacía
+func (l EventList) MarshalJSON() ([]byte, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(l))
+}
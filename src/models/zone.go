@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// Zone es una zona del zoológico a la que se puede asignar un usuario
+type Zone struct {
+	Name      string    `json:"name" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"createdAt"`
+}
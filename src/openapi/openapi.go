@@ -0,0 +1,409 @@
+// Package openapi arma la especificación OpenAPI 3 de la API a partir de las
+// mismas llamadas que registran las rutas, para que no se desactualice, y
+// valida peticiones y respuestas contra ella (ver Middleware)
+package openapi
+
+import (
+	"api3/src/problem"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3gen"
+)
+
+// TokenScheme es el esquema de seguridad de la API: el JWT va en ?token=
+const TokenScheme = "token"
+
+// StreamingExtension marca las operaciones con Streaming en la especificación
+const StreamingExtension = "x-streaming"
+
+// Auth es lo que exige una ruta para atender la petición
+type Auth int
+
+const (
+	Public Auth = iota
+	Authenticated
+	Admin
+	// Optional acepta peticiones sin token; si lo hay se valida y el
+	// controlador decide qué permite
+	Optional
+)
+
+// Param es un parámetro de query, de cabecera o de ruta. Type es string
+// (por defecto), integer o boolean.
+type Param struct {
+	Name        string
+	In          string // por defecto query
+	Description string
+	Type        string
+	Required    bool
+	Enum        []string
+}
+
+// Body es el cuerpo de la petición. Schema es un valor Go del que se genera
+// el esquema; se acepta como JSON, formulario y multipart, donde los campos
+// de Files son archivos. Raw son tipos de contenido que se envían tal cual
+// (p. ej. text/csv).
+type Body struct {
+	Schema      interface{}
+	Description string
+	Files       []string
+	Raw         []string
+	MergePatch  bool // admite además application/merge-patch+json y json-patch+json
+	JSONOnly    bool // solo application/json, sin formularios
+	NotRequired bool
+}
+
+// Response es una respuesta documentada. Sin Schema no tiene cuerpo, salvo
+// que ContentType indique uno binario o de texto.
+type Response struct {
+	Status      int
+	Description string
+	Schema      interface{}
+	ContentType []string // por defecto application/json
+	Headers     []string
+	Problem     bool // el mismo estado puede llevar también un problem+json
+}
+
+// Operation describe una ruta. Auth, IfMatch y Successor además de
+// documentarse deciden los middlewares que aplica quien la registra.
+type Operation struct {
+	ID          string
+	Summary     string
+	Description string
+	Tags        []string
+	Auth        Auth
+	IfMatch     bool
+	Successor   string // ruta que sustituye a esta, que queda obsoleta
+	Streaming   bool   // respuesta sin fin (SSE, WebSocket): no se valida
+	Params      []Param
+	Body        *Body
+	Responses   []Response
+}
+
+// Cabeceras de respuesta conocidas
+var headers = map[string]string{
+	"ETag":                "Versión del recurso, para If-Match / If-None-Match",
+	"Location":            "URL del recurso creado",
+	"X-Total-Count":       "Total de elementos que cumplen el filtro",
+	"Link":                "Enlaces a otras páginas o a la ruta que sustituye a esta",
+	"Content-Disposition": "Nombre del archivo descargado",
+	"Content-Range":       "Rango de bytes devuelto",
+	"Accept-Ranges":       "Indica que se admiten peticiones por rango",
+	"Content-Language":    "Idioma de los mensajes",
+	"Deprecation":         "Fecha en que la ruta quedó obsoleta (RFC 9745)",
+	"Sunset":              "Fecha de retirada de la ruta (RFC 8594)",
+}
+
+// Spec acumula las operaciones registradas
+type Spec struct {
+	doc     *openapi3.T
+	schemas openapi3.Schemas
+	errs    []string
+}
+
+func New(title, version, description string) *Spec {
+	s := &Spec{
+		doc: &openapi3.T{
+			OpenAPI: "3.0.3",
+			Info:    &openapi3.Info{Title: title, Version: version, Description: description},
+			Servers: openapi3.Servers{{URL: "/"}},
+			Paths:   openapi3.NewPaths(),
+			Components: &openapi3.Components{
+				SecuritySchemes: openapi3.SecuritySchemes{
+					TokenScheme: &openapi3.SecuritySchemeRef{Value: &openapi3.SecurityScheme{
+						Type:        "apiKey",
+						In:          "query",
+						Name:        "token",
+						Description: "JWT obtenido en POST /api/v1/auth/login",
+					}},
+				},
+			},
+		},
+		schemas: openapi3.Schemas{},
+	}
+	return s
+}
+
+// Opciones del generador de esquemas: cada tipo con nombre va a components
+var schemaOptions = []openapi3gen.Option{
+	openapi3gen.CreateComponentSchemas(openapi3gen.ExportComponentSchemasOptions{ExportComponentSchemas: true, ExportTopLevelSchema: true}),
+	openapi3gen.CreateTypeNameGenerator(func(t reflect.Type) string { return t.Name() }),
+	openapi3gen.SchemaCustomizer(customizeSchema),
+}
+
+// Variables de una ruta de gorilla/mux: {id} o {id:[0-9]+}
+var pathVar = regexp.MustCompile(`\{([^}:]+)(?::([^}]+))?\}`)
+
+// Add documenta la operación method de path, que usa la sintaxis de
+// gorilla/mux
+func (s *Spec) Add(method, path string, op Operation) {
+	o := openapi3.NewOperation()
+	o.Responses = openapi3.NewResponses()
+	o.OperationID = op.ID
+	o.Summary = op.Summary
+	o.Description = op.Description
+	o.Tags = op.Tags
+	o.Deprecated = op.Successor != ""
+	if op.Streaming {
+		o.Extensions = map[string]interface{}{StreamingExtension: true}
+	}
+	if o.Deprecated {
+		o.Description = strings.TrimSpace(o.Description + "\n\nRuta obsoleta: usa " + op.Successor + ".")
+	}
+
+	for _, m := range pathVar.FindAllStringSubmatch(path, -1) {
+		schema := openapi3.NewStringSchema()
+		if m[2] == "[0-9]+" {
+			schema = openapi3.NewIntegerSchema()
+		} else if m[2] != "" {
+			schema.Pattern = "^" + m[2] + "$"
+		}
+		o.AddParameter(&openapi3.Parameter{Name: m[1], In: openapi3.ParameterInPath, Required: true, Schema: schema.NewRef()})
+	}
+	path = pathVar.ReplaceAllString(path, "{$1}")
+	for _, p := range op.Params {
+		o.AddParameter(s.param(p))
+	}
+
+	switch op.Auth {
+	case Optional:
+		o.Security = &openapi3.SecurityRequirements{{}, {TokenScheme: []string{}}}
+		s.addResponse(o, op, Response{Status: http.StatusUnauthorized, Description: "El token no es válido"})
+	case Authenticated, Admin:
+		o.Security = &openapi3.SecurityRequirements{{TokenScheme: []string{}}}
+		s.addResponse(o, op, Response{Status: http.StatusUnauthorized, Description: "Falta el token o no es válido"})
+		if op.Auth == Admin {
+			o.Description = strings.TrimSpace("Requiere rol admin. " + o.Description)
+			s.addResponse(o, op, Response{Status: http.StatusForbidden, Description: "Requiere rol admin"})
+		}
+	}
+	if op.IfMatch {
+		o.AddParameter(s.param(Param{Name: "If-Match", In: openapi3.ParameterInHeader, Description: "ETag leído; si no coincide con la versión actual responde 412 con el estado actual. Obligatorio con REQUIRE_IF_MATCH=true (si falta, 428)"}))
+		s.addResponse(o, op, Response{Status: http.StatusPreconditionFailed, Description: "La versión cambió"})
+		s.addResponse(o, op, Response{Status: http.StatusPreconditionRequired, Description: "Falta If-Match"})
+	}
+	if op.Body != nil {
+		o.RequestBody = s.body(op.Body)
+	}
+	for _, resp := range op.Responses {
+		s.addResponse(o, op, resp)
+	}
+	o.Responses.Set("default", s.problemResponse("Error (problem+json)"))
+
+	s.doc.AddOperation(path, method, o)
+}
+
+func (s *Spec) param(p Param) *openapi3.Parameter {
+	var schema *openapi3.Schema
+	switch p.Type {
+	case "integer":
+		schema = openapi3.NewIntegerSchema()
+	case "boolean":
+		schema = openapi3.NewBoolSchema()
+	default:
+		schema = openapi3.NewStringSchema()
+		for _, v := range p.Enum {
+			schema.Enum = append(schema.Enum, v)
+		}
+	}
+	in := p.In
+	if in == "" {
+		in = openapi3.ParameterInQuery
+	}
+	return &openapi3.Parameter{Name: p.Name, In: in, Description: p.Description, Required: p.Required, Schema: schema.NewRef()}
+}
+
+func (s *Spec) body(b *Body) *openapi3.RequestBodyRef {
+	body := openapi3.NewRequestBody().WithDescription(b.Description).WithRequired(!b.NotRequired)
+	if b.Schema != nil {
+		ref := s.schema(b.Schema)
+		body.Content = openapi3.NewContentWithJSONSchemaRef(ref)
+		if b.MergePatch {
+			body.Content["application/merge-patch+json"] = openapi3.NewMediaType().WithSchemaRef(ref)
+			body.Content["application/json-patch+json"] = openapi3.NewMediaType().WithSchema(openapi3.NewArraySchema().WithItems(openapi3.NewObjectSchema()))
+		} else if !b.JSONOnly {
+			body.Content["application/x-www-form-urlencoded"] = openapi3.NewMediaType().WithSchemaRef(ref)
+		}
+	}
+	if len(b.Files) > 0 || !b.MergePatch && !b.JSONOnly && b.Schema != nil {
+		// multipart: los campos del esquema más los archivos
+		form := openapi3.NewObjectSchema()
+		if b.Schema != nil {
+			if value := s.resolve(s.schema(b.Schema)); value != nil {
+				for name, prop := range value.Properties {
+					form.WithPropertyRef(name, prop)
+				}
+				form.Required = value.Required
+			}
+		}
+		for _, name := range b.Files {
+			form.WithProperty(name, openapi3.NewStringSchema().WithFormat("binary"))
+		}
+		if body.Content == nil {
+			body.Content = openapi3.Content{}
+		}
+		body.Content["multipart/form-data"] = openapi3.NewMediaType().WithSchema(form)
+	}
+	for _, contentType := range b.Raw {
+		if body.Content == nil {
+			body.Content = openapi3.Content{}
+		}
+		body.Content[contentType] = openapi3.NewMediaType().WithSchema(openapi3.NewStringSchema().WithFormat("binary"))
+	}
+	return &openapi3.RequestBodyRef{Value: body}
+}
+
+func (s *Spec) addResponse(o *openapi3.Operation, op Operation, r Response) {
+	status := strconv.Itoa(r.Status)
+	if r.Status >= 400 && r.Schema == nil {
+		o.Responses.Set(status, s.problemResponse(r.Description))
+		return
+	}
+
+	resp := openapi3.NewResponse().WithDescription(r.Description)
+	contentTypes := r.ContentType
+	if len(contentTypes) == 0 && r.Schema != nil {
+		contentTypes = []string{"application/json"}
+	}
+	for _, contentType := range contentTypes {
+		var media *openapi3.MediaType
+		switch {
+		case r.Schema != nil:
+			media = openapi3.NewMediaType().WithSchemaRef(s.schema(r.Schema))
+		case strings.HasPrefix(contentType, "text/plain"):
+			media = openapi3.NewMediaType().WithSchema(openapi3.NewStringSchema())
+		default:
+			media = openapi3.NewMediaType().WithSchema(openapi3.NewStringSchema().WithFormat("binary"))
+		}
+		if resp.Content == nil {
+			resp.Content = openapi3.Content{}
+		}
+		resp.Content[contentType] = media
+	}
+	if r.Problem {
+		resp.Content[problem.ContentType] = s.problemResponse("").Value.Content[problem.ContentType]
+	}
+
+	names := r.Headers
+	if op.Successor != "" {
+		names = append(names[:len(names):len(names)], "Deprecation", "Sunset", "Link")
+	}
+	for _, name := range names {
+		description, ok := headers[name]
+		if !ok {
+			s.errs = append(s.errs, fmt.Sprintf("%s: cabecera %s sin describir", op.ID, name))
+		}
+		if resp.Headers == nil {
+			resp.Headers = openapi3.Headers{}
+		}
+		resp.Headers[name] = &openapi3.HeaderRef{Value: &openapi3.Header{Parameter: openapi3.Parameter{
+			Description: description,
+			Schema:      openapi3.NewStringSchema().NewRef(),
+		}}}
+	}
+	o.Responses.Set(status, &openapi3.ResponseRef{Value: resp})
+}
+
+func (s *Spec) problemResponse(description string) *openapi3.ResponseRef {
+	resp := openapi3.NewResponse().WithDescription(description)
+	resp.Content = openapi3.Content{problem.ContentType: openapi3.NewMediaType().WithSchemaRef(s.schema(problem.Problem{}))}
+	return &openapi3.ResponseRef{Value: resp}
+}
+
+// schema genera el esquema de value y el de los tipos que usa en components
+func (s *Spec) schema(value interface{}) *openapi3.SchemaRef {
+	// Un generador por valor: el de kin-openapi no está pensado para reusarse
+	ref, err := openapi3gen.NewSchemaRefForValue(value, s.schemas, schemaOptions...)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Sprintf("esquema de %T: %v", value, err))
+		return openapi3.NewObjectSchema().NewRef()
+	}
+	return ref
+}
+
+// resolve devuelve el esquema al que apunta ref
+func (s *Spec) resolve(ref *openapi3.SchemaRef) *openapi3.Schema {
+	if ref.Value != nil {
+		return ref.Value
+	}
+	if target := s.schemas[strings.TrimPrefix(ref.Ref, "#/components/schemas/")]; target != nil {
+		return target.Value
+	}
+	return nil
+}
+
+// Document devuelve la especificación completa y validada
+func (s *Spec) Document() (*openapi3.T, error) {
+	if len(s.errs) > 0 {
+		return nil, fmt.Errorf("especificación OpenAPI: %s", strings.Join(s.errs, "; "))
+	}
+	s.doc.Components.Schemas = s.schemas
+	loader := openapi3.NewLoader()
+	if err := loader.ResolveRefsIn(s.doc, nil); err != nil {
+		return nil, fmt.Errorf("especificación OpenAPI: %w", err)
+	}
+	if err := s.doc.Validate(loader.Context); err != nil {
+		return nil, fmt.Errorf("especificación OpenAPI: %w", err)
+	}
+	return s.doc, nil
+}
+
+// customizeSchema completa los esquemas generados con lo que no se deduce
+// del tipo de Go: qué campos son obligatorios y cuáles pueden ser null. El
+// resto de reglas de validación no se copian; las aplica cada controlador
+// para responder con errores por campo.
+func customizeSchema(name string, t reflect.Type, tag reflect.StructTag, schema *openapi3.Schema) error {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		schema.Nullable = true
+	case reflect.Struct:
+		if schema.Properties != nil {
+			schema.Required = requiredFields(t, isInput(t))
+		}
+	}
+	return nil
+}
+
+// Los datos de entrada llevan etiquetas validate
+func isInput(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if _, ok := f.Tag.Lookup("validate"); ok {
+			return true
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && isInput(f.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// Campos obligatorios de t: en una entrada los que validate exige; en una
+// respuesta los que siempre aparecen (sin omitempty)
+func requiredFields(t reflect.Type, input bool) []string {
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			required = append(required, requiredFields(f.Type, input)...)
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" || name == "" {
+			continue
+		}
+		if input && strings.HasPrefix(f.Tag.Get("validate"), "required") ||
+			!input && !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(required)
+	return required
+}
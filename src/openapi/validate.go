@@ -0,0 +1,137 @@
+package openapi
+
+import (
+	"api3/src/problem"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+// Qué valida Middleware
+const (
+	ValidateRequests  = "requests"
+	ValidateResponses = "responses"
+	ValidateAll       = "all"
+)
+
+// Middleware valida las peticiones y las respuestas de las rutas
+// documentadas contra doc. Está pensado para desarrollo y pruebas
+// (OPENAPI_VALIDATE): una petición que no cumple se rechaza con 400 y una
+// respuesta que no cumple se registra y se sustituye por un 500, para que
+// el desajuste no pase desapercibido. mode es ValidateRequests,
+// ValidateResponses o ValidateAll.
+func Middleware(doc *openapi3.T, mode string) (func(http.Handler) http.Handler, error) {
+	if mode != ValidateRequests && mode != ValidateResponses && mode != ValidateAll {
+		return nil, fmt.Errorf("modo de validación desconocido %q (requests, responses o all)", mode)
+	}
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+	requests := mode != ValidateResponses
+	responses := mode != ValidateRequests
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, params, err := router.FindRoute(r)
+			if err != nil {
+				// Ruta sin documentar (p. ej. /swagger/): no hay contra qué validar
+				next.ServeHTTP(w, r)
+				return
+			}
+			input := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: params,
+				Route:      route,
+				// La autenticación la resuelven los controladores con sus
+				// propios errores (401/403)
+				Options: &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+			}
+			if requests {
+				if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+					problem.Write(w, r, problem.New(problem.CodeRequestInvalid).WithDetail("detail.openapi", validationMessage(err)))
+					return
+				}
+			}
+			if !responses || route.Operation.Extensions[StreamingExtension] == true {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rec := &recorder{header: http.Header{}, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			if err := validateResponse(r, input, rec); err != nil {
+				log.Printf("❌ Respuesta de %s %s fuera de la especificación: %v", r.Method, route.Path, err)
+				problem.Write(w, r, problem.New(problem.CodeResponseInvalid).WithDetail("detail.openapi", validationMessage(err)))
+				return
+			}
+			rec.copyTo(w)
+		})
+	}, nil
+}
+
+func validateResponse(r *http.Request, input *openapi3filter.RequestValidationInput, rec *recorder) error {
+	// Los cuerpos binarios (imágenes, exportaciones) no se validan
+	mediaType, _, _ := mime.ParseMediaType(rec.header.Get("Content-Type"))
+	json := mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+	opts := &openapi3filter.Options{IncludeResponseStatus: true, ExcludeResponseBody: !json}
+	if rec.status == http.StatusNotModified || r.Method == http.MethodHead {
+		opts.ExcludeResponseBody = true
+	}
+	return openapi3filter.ValidateResponse(r.Context(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 rec.status,
+		Header:                 rec.header,
+		Body:                   io.NopCloser(bytes.NewReader(rec.body.Bytes())),
+		Options:                opts,
+	})
+}
+
+// Mensaje del error sin el esquema completo que añade kin-openapi
+func validationMessage(err error) string {
+	msg := err.Error()
+	if i := strings.Index(msg, "\nSchema:"); i >= 0 {
+		msg = msg[:i]
+	}
+	return strings.TrimSpace(msg)
+}
+
+// recorder guarda la respuesta para validarla antes de enviarla
+type recorder struct {
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (rec *recorder) Header() http.Header { return rec.header }
+
+func (rec *recorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status, rec.wroteHeader = status, true
+	}
+}
+
+func (rec *recorder) Write(p []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	return rec.body.Write(p)
+}
+
+// Flush no hace nada: la respuesta sale entera tras validarla
+func (rec *recorder) Flush() {}
+
+func (rec *recorder) copyTo(w http.ResponseWriter) {
+	for name, values := range rec.header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(rec.status)
+	w.Write(rec.body.Bytes())
+}
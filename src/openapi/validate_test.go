@@ -0,0 +1,80 @@
+package openapi
+
+import (
+	"api3/src/problem"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type zoneInput struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type zone struct {
+	Name string `json:"name"`
+}
+
+func testMiddleware(t *testing.T, mode string, response string) http.Handler {
+	t.Helper()
+	spec := New("test", "1.0", "")
+	spec.Add("POST", "/zones", Operation{
+		ID:        "createZone",
+		Auth:      Admin,
+		Body:      &Body{Schema: zoneInput{}},
+		Responses: []Response{{Status: http.StatusCreated, Description: "Zona creada", Schema: zone{}}},
+	})
+	doc, err := spec.Document()
+	if err != nil {
+		t.Fatal(err)
+	}
+	validate, err := Middleware(doc, mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return validate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(response))
+	}))
+}
+
+func serve(h http.Handler, body string) (int, string) {
+	req := httptest.NewRequest("POST", "/zones?token=x", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var p problem.Problem
+	json.Unmarshal(rec.Body.Bytes(), &p)
+	return rec.Code, p.Code
+}
+
+func TestMiddlewareRequests(t *testing.T) {
+	h := testMiddleware(t, ValidateRequests, `{"name":"norte"}`)
+	if status, _ := serve(h, `{"name":"norte"}`); status != http.StatusCreated {
+		t.Errorf("petición válida: estado %d", status)
+	}
+	for _, body := range []string{`{"name":5}`, `{}`} {
+		if status, code := serve(h, body); status != http.StatusBadRequest || code != problem.CodeRequestInvalid {
+			t.Errorf("%s: estado %d código %q, se esperaba 400 %s", body, status, code, problem.CodeRequestInvalid)
+		}
+	}
+}
+
+func TestMiddlewareResponses(t *testing.T) {
+	if status, _ := serve(testMiddleware(t, ValidateResponses, `{"name":"norte"}`), `{}`); status != http.StatusCreated {
+		t.Errorf("respuesta válida: estado %d", status)
+	}
+	status, code := serve(testMiddleware(t, ValidateResponses, `{"nombre":"norte"}`), `{}`)
+	if status != http.StatusInternalServerError || code != problem.CodeResponseInvalid {
+		t.Errorf("respuesta inválida: estado %d código %q", status, code)
+	}
+}
+
+func TestMiddlewareUnknownMode(t *testing.T) {
+	if _, err := Middleware(nil, "todo"); err == nil {
+		t.Error("se esperaba error con un modo desconocido")
+	}
+}
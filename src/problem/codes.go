@@ -0,0 +1,114 @@
+package problem
+
+import "net/http"
+
+// Códigos de error estables
+const (
+	CodeInvalidBody          = "invalid_body"
+	CodeInvalidID            = "invalid_id"
+	CodeInvalidQuery         = "invalid_query"
+	CodeValidation           = "validation_failed"
+	CodeUserNotFound         = "user_not_found"
+	CodeUserExists           = "user_exists"
+	CodeInvalidCredentials   = "invalid_credentials"
+	CodeTokenMissing         = "token_missing"
+	CodeTokenInvalid         = "token_invalid"
+	CodeForbidden            = "forbidden"
+	CodeImageNotFound        = "image_not_found"
+	CodeImageInvalid         = "image_invalid"
+	CodeImageUnsupported     = "image_unsupported"
+	CodeImageTooLarge        = "image_too_large"
+	CodeUnsupportedMedia     = "unsupported_media_type"
+	CodeInvalidPatch         = "invalid_patch"
+	CodePatchConflict        = "patch_conflict"
+	CodePreconditionFailed   = "precondition_failed"
+	CodePreconditionRequired = "precondition_required"
+	CodeVersionConflict      = "version_conflict"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeZoneExists           = "zone_exists"
+	CodeImportInvalid        = "import_invalid"
+	CodeInvitationInvalid    = "invitation_invalid"
+	CodeRequestInvalid       = "request_invalid"
+	CodeResponseInvalid      = "response_invalid"
+	CodeQueryTooDeep         = "query_too_deep"
+	CodeQueryTooComplex      = "query_too_complex"
+	CodeWebhookNotFound      = "webhook_not_found"
+	CodeDeliveryNotFound     = "delivery_not_found"
+	CodeInternal             = "internal_error"
+)
+
+// Códigos de los errores por campo
+const (
+	FieldRequired      = "required"
+	FieldEmpty         = "empty"
+	FieldNull          = "null"
+	FieldUnknown       = "unknown"
+	FieldNotString     = "not_string"
+	FieldInvalidBase64 = "invalid_base64"
+	FieldInvalidStatus = "invalid_status"
+	FieldInvalidValue  = "invalid_value"
+	FieldOutOfRange    = "out_of_range"
+	FieldConflict      = "conflict"
+	FieldTooShort      = "too_short"
+	FieldTooLong       = "too_long"
+	FieldInvalidFormat = "invalid_format"
+	FieldInvalidRole   = "invalid_role"
+	FieldUnknownZona   = "unknown_zona"
+	FieldDuplicate     = "duplicate"
+	FieldInvalidJSON   = "invalid_json"
+	FieldInvalidURL    = "invalid_url"
+	FieldInvalidEvent  = "invalid_event"
+)
+
+// Estado HTTP de cada código. Los títulos y mensajes están en el catálogo
+// de i18n con las claves problem.<código> y field.<código>.
+var statuses = map[string]int{
+	CodeInvalidBody:          http.StatusBadRequest,
+	CodeInvalidID:            http.StatusBadRequest,
+	CodeInvalidQuery:         http.StatusBadRequest,
+	CodeValidation:           http.StatusUnprocessableEntity,
+	CodeUserNotFound:         http.StatusNotFound,
+	CodeUserExists:           http.StatusConflict,
+	CodeInvalidCredentials:   http.StatusUnauthorized,
+	CodeTokenMissing:         http.StatusUnauthorized,
+	CodeTokenInvalid:         http.StatusUnauthorized,
+	CodeForbidden:            http.StatusForbidden,
+	CodeImageNotFound:        http.StatusNotFound,
+	CodeImageInvalid:         http.StatusBadRequest,
+	CodeImageUnsupported:     http.StatusUnsupportedMediaType,
+	CodeImageTooLarge:        http.StatusRequestEntityTooLarge,
+	CodeUnsupportedMedia:     http.StatusUnsupportedMediaType,
+	CodeInvalidPatch:         http.StatusBadRequest,
+	CodePatchConflict:        http.StatusConflict,
+	CodePreconditionFailed:   http.StatusPreconditionFailed,
+	CodePreconditionRequired: http.StatusPreconditionRequired,
+	CodeVersionConflict:      http.StatusConflict,
+	CodeNotFound:             http.StatusNotFound,
+	CodeMethodNotAllowed:     http.StatusMethodNotAllowed,
+	CodeZoneExists:           http.StatusConflict,
+	CodeImportInvalid:        http.StatusBadRequest,
+	CodeInvitationInvalid:    http.StatusGone,
+	CodeRequestInvalid:       http.StatusBadRequest,
+	CodeResponseInvalid:      http.StatusInternalServerError,
+	CodeQueryTooDeep:         http.StatusBadRequest,
+	CodeQueryTooComplex:      http.StatusBadRequest,
+	CodeWebhookNotFound:      http.StatusNotFound,
+	CodeDeliveryNotFound:     http.StatusNotFound,
+	CodeInternal:             http.StatusInternalServerError,
+}
+
+// Todos los códigos de error por campo
+var fieldCodes = []string{
+	FieldRequired, FieldEmpty, FieldNull, FieldUnknown, FieldNotString,
+	FieldInvalidBase64, FieldInvalidStatus, FieldInvalidValue, FieldOutOfRange, FieldConflict,
+	FieldTooShort, FieldTooLong, FieldInvalidFormat, FieldInvalidRole, FieldUnknownZona,
+	FieldDuplicate, FieldInvalidJSON, FieldInvalidURL, FieldInvalidEvent,
+}
+
+func statusOf(code string) int {
+	if status, ok := statuses[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,25 @@
+package problem
+
+import (
+	"api3/src/i18n"
+	"testing"
+)
+
+// Cada código de error y de campo debe tener su texto en todos los idiomas
+func TestCodesTranslated(t *testing.T) {
+	var keys []string
+	for code := range statuses {
+		keys = append(keys, "problem."+code)
+	}
+	for _, code := range fieldCodes {
+		keys = append(keys, "field."+code)
+	}
+
+	for _, locale := range i18n.Locales() {
+		for _, key := range keys {
+			if got := i18n.T(locale, key); got == key {
+				t.Errorf("%s: falta la traducción de %q", locale, key)
+			}
+		}
+	}
+}
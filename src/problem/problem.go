@@ -0,0 +1,143 @@
+// Package problem define los errores de la API y los escribe como
+// application/problem+json (RFC 7807)
+package problem
+
+import (
+	"api3/src/i18n"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+)
+
+// ContentType es el tipo de las respuestas de error
+const ContentType = "application/problem+json"
+
+// FieldError es un error de validación de un campo concreto
+type FieldError struct {
+	Field  string `json:"field"`
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+
+	args []interface{}
+}
+
+// Field crea un error de campo; args son los datos del mensaje (p. ej. la
+// longitud mínima)
+func Field(name, code string, args ...interface{}) FieldError {
+	return FieldError{Field: name, Code: code, args: args}
+}
+
+// Problem es el error que devuelven los controladores. Code es estable y es
+// lo que debe comparar el cliente; Title y Detail se traducen al escribirlo.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+	// Estado actual del recurso cuando falla una precondición
+	Current interface{} `json:"current,omitempty"`
+
+	detailKey  string
+	detailArgs []interface{}
+	cause      error
+}
+
+// New crea un problema con el estado HTTP que corresponde al código
+func New(code string) *Problem {
+	return &Problem{Code: code, Status: statusOf(code)}
+}
+
+// Internal envuelve un error inesperado. La causa se registra en el log pero
+// no se envía al cliente.
+func Internal(err error) *Problem {
+	p := New(CodeInternal)
+	p.cause = err
+	return p
+}
+
+// Validation crea un problema con un error por campo; fields asocia cada
+// campo con un código Field*
+func Validation(fields map[string]string) *Problem {
+	return New(CodeValidation).WithFields(fields)
+}
+
+// WithDetail indica la clave del catálogo (y sus datos) para el detalle
+func (p *Problem) WithDetail(key string, args ...interface{}) *Problem {
+	p.detailKey, p.detailArgs = key, args
+	return p
+}
+
+// WithFields añade errores por campo (ordenados por nombre)
+func (p *Problem) WithFields(fields map[string]string) *Problem {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p.Errors = append(p.Errors, Field(name, fields[name]))
+	}
+	return p
+}
+
+// WithFieldErrors añade errores por campo ya construidos con Field
+func (p *Problem) WithFieldErrors(errs ...FieldError) *Problem {
+	p.Errors = append(p.Errors, errs...)
+	return p
+}
+
+// WithCurrent adjunta el estado actual del recurso
+func (p *Problem) WithCurrent(current interface{}) *Problem {
+	p.Current = current
+	return p
+}
+
+func (p *Problem) Error() string {
+	if p.detailKey != "" {
+		return p.Code + ": " + i18n.T(i18n.FallbackLocale, p.detailKey, p.detailArgs...)
+	}
+	return p.Code
+}
+
+func (p *Problem) Unwrap() error { return p.cause }
+
+// Write traduce el problema al idioma de la petición y lo escribe como
+// application/problem+json
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+	if p.cause != nil {
+		log.Printf("❌ %s %s: %v", r.Method, r.URL.Path, p.cause)
+	}
+	locale := i18n.FromRequest(r)
+	p.Translate(locale)
+	p.Instance = r.URL.Path
+
+	i18n.SetHeaders(w, locale)
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Translate rellena Type, Title, Detail y los detalles de los errores de
+// campo en el idioma dado; Write lo hace solo, el resto de transportes
+// (GraphQL, gRPC) lo llaman antes de convertir el problema
+func (p *Problem) Translate(locale string) {
+	p.Type = "/problems/" + p.Code
+	p.Title = i18n.T(locale, "problem."+p.Code)
+	if p.detailKey != "" {
+		p.Detail = i18n.T(locale, p.detailKey, p.detailArgs...)
+	}
+	Localize(locale, p.Errors)
+}
+
+// Localize traduce el detalle de cada error de campo; sirve también para
+// respuestas que no son un Problem (p. ej. el informe de una importación)
+func Localize(locale string, errs []FieldError) {
+	for i := range errs {
+		errs[i].Detail = i18n.T(locale, "field."+errs[i].Code, errs[i].args...)
+	}
+}
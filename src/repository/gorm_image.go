@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"api3/db"
+	"api3/src/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GormImageRepository guarda las referencias en la tabla images. Todas las
+// operaciones van a la BD principal.
+type GormImageRepository struct {
+	cluster *db.Cluster
+}
+
+func NewGormImageRepository(cluster *db.Cluster) *GormImageRepository {
+	return &GormImageRepository{cluster: cluster}
+}
+
+func (r *GormImageRepository) Acquire(ctx context.Context, image *models.Image) (bool, error) {
+	tx := r.cluster.Writer(ctx)
+	for attempt := 1; attempt <= 5; attempt++ {
+		now := time.Now().UTC()
+		result := tx.Model(&models.Image{}).Where("hash = ? AND ref_count >= 0", image.Hash).
+			Updates(map[string]interface{}{"ref_count": gorm.Expr("ref_count + 1"), "updated_at": now})
+		if result.Error != nil {
+			return false, result.Error
+		}
+		if result.RowsAffected > 0 {
+			return false, nil
+		}
+
+		row := *image
+		row.RefCount = 1
+		row.CreatedAt, row.UpdatedAt = now, now
+		err := tx.Create(&row).Error
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return false, err
+		}
+
+		// Otra petición la acaba de crear (el próximo UPDATE la encontrará)
+		// o el recolector la está borrando: se espera un poco
+		time.Sleep(time.Duration(attempt) * 50 * time.Millisecond)
+	}
+	return false, ErrImageBusy
+}
+
+func (r *GormImageRepository) Release(ctx context.Context, hash string) error {
+	return r.cluster.Writer(ctx).Model(&models.Image{}).Where("hash = ? AND ref_count > 0", hash).
+		Updates(map[string]interface{}{"ref_count": gorm.Expr("ref_count - 1"), "updated_at": time.Now().UTC()}).Error
+}
+
+func (r *GormImageRepository) ClaimOrphans(ctx context.Context, olderThan time.Time, limit int) ([]string, error) {
+	tx := r.cluster.Writer(ctx)
+
+	// También se recogen marcas viejas de una recolección que se interrumpió
+	var candidates []string
+	err := tx.Model(&models.Image{}).Where("ref_count <= 0 AND updated_at < ?", olderThan).
+		Limit(limit).Pluck("hash", &candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var claimed []string
+	for _, hash := range candidates {
+		result := tx.Model(&models.Image{}).Where("hash = ? AND ref_count <= 0 AND updated_at < ?", hash, olderThan).
+			Updates(map[string]interface{}{"ref_count": tombstone, "updated_at": time.Now().UTC()})
+		if result.Error != nil {
+			return claimed, result.Error
+		}
+		if result.RowsAffected > 0 {
+			claimed = append(claimed, hash)
+		}
+	}
+	return claimed, nil
+}
+
+func (r *GormImageRepository) Forget(ctx context.Context, hash string) error {
+	return r.cluster.Writer(ctx).Where("hash = ? AND ref_count = ?", hash, tombstone).Delete(&models.Image{}).Error
+}
@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"api3/db"
+	"api3/src/models"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormUserRepository guarda los usuarios usando GORM. Las lecturas pueden
+// ir a una réplica; las escrituras siempre van a la BD principal.
+type GormUserRepository struct {
+	cluster *db.Cluster
+}
+
+func NewGormUserRepository(cluster *db.Cluster) *GormUserRepository {
+	return &GormUserRepository{cluster: cluster}
+}
+
+func (r *GormUserRepository) Create(ctx context.Context, user *models.User) error {
+	defer r.cluster.MarkWrite(ctx)
+	if user.Version == 0 {
+		user.Version = 1
+	}
+	return translateError(r.cluster.Writer(ctx).Create(user).Error)
+}
+
+func (r *GormUserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	var user models.User
+	err := r.read(ctx, func(tx *gorm.DB) error {
+		return tx.First(&user, id).Error
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+func (r *GormUserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	var user models.User
+	err := r.read(ctx, func(tx *gorm.DB) error {
+		return tx.Where("username = ?", username).First(&user).Error
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+func (r *GormUserRepository) List(ctx context.Context, q ListQuery) (*UserPage, error) {
+	sort := effectiveSort(q.Sort)
+	backward := q.Cursor != nil && q.Cursor.Backward
+
+	var cursorValues []interface{}
+	if q.Cursor != nil {
+		var err error
+		if cursorValues, err = normalizeCursor(q.Cursor, sort); err != nil {
+			return nil, err
+		}
+	}
+
+	var users []models.User
+	var total int64
+	err := r.read(ctx, func(tx *gorm.DB) error {
+		filtered := applyFilter(tx.Model(&models.User{}), q.Filter)
+		if err := filtered.Count(&total).Error; err != nil {
+			return err
+		}
+
+		query := applyFilter(tx.Model(&models.User{}), q.Filter)
+		for _, f := range sort {
+			// Hacia atrás se invierte el orden y luego se dan vuelta las filas
+			desc := f.Desc != backward
+			query = query.Order(clause.OrderByColumn{Column: clause.Column{Name: f.Column}, Desc: desc})
+		}
+		if cursorValues != nil {
+			where, args := keysetCondition(sort, cursorValues, backward)
+			query = query.Where(where, args...)
+		} else if q.Offset > 0 {
+			query = query.Offset(q.Offset)
+		}
+		return query.Limit(q.Limit + 1).Find(&users).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buildPage(users, q, sort, total), nil
+}
+
+func (r *GormUserRepository) Stream(ctx context.Context, filter UserFilter, sort []SortField, fn func(user *models.User) error) error {
+	tx := r.cluster.Reader(ctx)
+	query := applyFilter(tx.Model(&models.User{}), filter)
+	for _, f := range effectiveSort(sort) {
+		query = query.Order(clause.OrderByColumn{Column: clause.Column{Name: f.Column}, Desc: f.Desc})
+	}
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user models.User
+		if err := tx.ScanRows(rows, &user); err != nil {
+			return err
+		}
+		if err := fn(&user); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Misma expresión que el índice idx_users_search de PostgreSQL
+const postgresSearchVector = `to_tsvector('simple', coalesce(username, '') || ' ' || display_name || ' ' || coalesce(zona, ''))`
+
+func (r *GormUserRepository) Search(ctx context.Context, terms []string, limit int) ([]models.User, error) {
+	var users []models.User
+	err := r.read(ctx, func(tx *gorm.DB) error {
+		switch tx.Dialector.Name() {
+		case db.DriverPostgres:
+			// Los términos ya vienen normalizados (solo letras y dígitos)
+			query := strings.Join(terms, ":* & ") + ":*"
+			return tx.Where(postgresSearchVector+" @@ to_tsquery('simple', ?)", query).
+				Order(clause.Expr{SQL: "ts_rank(" + postgresSearchVector + ", to_tsquery('simple', ?)) DESC", Vars: []interface{}{query}}).
+				Limit(limit).Find(&users).Error
+		case db.DriverMySQL:
+			query := "+" + strings.Join(terms, "* +") + "*"
+			match := "MATCH(username, display_name, zona) AGAINST (? IN BOOLEAN MODE)"
+			return tx.Where(match, query).
+				Order(clause.Expr{SQL: match + " DESC", Vars: []interface{}{query}}).
+				Limit(limit).Find(&users).Error
+		}
+		return ErrSearchUnsupported
+	})
+	return users, err
+}
+
+func (r *GormUserRepository) Update(ctx context.Context, user *models.User) error {
+	defer r.cluster.MarkWrite(ctx)
+	now := time.Now().UTC()
+	res := r.cluster.Writer(ctx).Model(&models.User{}).Where("id = ? AND version = ?", user.ID, user.Version).
+		Updates(map[string]interface{}{
+			"username":     user.Username,
+			"display_name": user.DisplayName,
+			"password":     user.Password,
+			"role":         user.Role,
+			"zona":         user.Zona,
+			"status":       user.Status,
+			"image_key":    user.ImageKey,
+			"version":      gorm.Expr("version + 1"),
+			"updated_at":   now,
+		})
+	if res.Error != nil {
+		return translateError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	user.Version++
+	user.UpdatedAt = now
+	return nil
+}
+
+func (r *GormUserRepository) Delete(ctx context.Context, id int, version int64) error {
+	defer r.cluster.MarkWrite(ctx)
+	tx := r.cluster.Writer(ctx).Where("id = ?", id)
+	if version > 0 {
+		tx = tx.Where("version = ?", version)
+	}
+	res := tx.Delete(&models.User{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if version > 0 && res.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+func (r *GormUserRepository) Transaction(ctx context.Context, fn func(tx UserRepository) error) error {
+	defer r.cluster.MarkWrite(ctx)
+	return r.cluster.Writer(ctx).Transaction(func(tx *gorm.DB) error {
+		// Dentro de la transacción todo, también las lecturas, va por tx
+		return fn(&GormUserRepository{cluster: db.NewCluster(tx, 0)})
+	})
+}
+
+// Ejecuta una lectura en una réplica y, si falla por algo distinto a "no
+// encontrado", la repite en la BD principal
+func (r *GormUserRepository) read(ctx context.Context, query func(tx *gorm.DB) error) error {
+	tx := r.cluster.Reader(ctx)
+	err := query(tx)
+	if err == nil || errors.Is(err, gorm.ErrRecordNotFound) || r.cluster.IsPrimary(tx) {
+		return err
+	}
+	return query(r.cluster.Writer(ctx))
+}
+
+func applyFilter(tx *gorm.DB, f UserFilter) *gorm.DB {
+	if f.Role != "" {
+		tx = tx.Where("role = ?", f.Role)
+	}
+	if f.Zona != "" {
+		tx = tx.Where("zona = ?", f.Zona)
+	}
+	if f.Status != "" {
+		tx = tx.Where("status = ?", f.Status)
+	}
+	if f.UsernamePrefix != "" {
+		tx = tx.Where("username LIKE ? ESCAPE '!'", escapeLike(f.UsernamePrefix)+"%")
+	}
+	return tx
+}
+
+// Escapa los comodines de LIKE para que el prefijo se tome literal
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// Condición keyset para seguir después (o antes) de los valores del cursor
+// respetando el sentido de cada columna:
+// (a > x) OR (a = x AND b > y) OR ...
+// Las columnas ordenables son NOT NULL (migración 11): un NULL nunca cumple
+// la comparación y la fila se saltaría.
+func keysetCondition(sort []SortField, values []interface{}, backward bool) (string, []interface{}) {
+	var ors []string
+	var args []interface{}
+	for i, f := range sort {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, sort[j].Column+" = ?")
+			args = append(args, values[j])
+		}
+		op := ">"
+		if f.Desc != backward {
+			op = "<"
+		}
+		ands = append(ands, f.Column+" "+op+" ?")
+		args = append(args, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+// Convierte los errores de GORM en los errores del paquete
+func translateError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicate
+	}
+	return err
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"api3/db"
+	"api3/src/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GormWebhookRepository guarda los webhooks en las tablas webhooks,
+// webhook_deliveries y webhook_attempts. Todas las operaciones van a la BD
+// principal: el repartidor no puede leer entregas atrasadas de una réplica.
+type GormWebhookRepository struct {
+	cluster *db.Cluster
+}
+
+func NewGormWebhookRepository(cluster *db.Cluster) *GormWebhookRepository {
+	return &GormWebhookRepository{cluster: cluster}
+}
+
+func (r *GormWebhookRepository) List(ctx context.Context) ([]models.Webhook, error) {
+	var hooks []models.Webhook
+	err := r.cluster.Writer(ctx).Order("id").Find(&hooks).Error
+	return hooks, err
+}
+
+func (r *GormWebhookRepository) Get(ctx context.Context, id int) (*models.Webhook, error) {
+	var hook models.Webhook
+	err := r.cluster.Writer(ctx).First(&hook, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWebhookNotFound
+	}
+	return &hook, err
+}
+
+func (r *GormWebhookRepository) Create(ctx context.Context, hook *models.Webhook) error {
+	now := time.Now().UTC()
+	hook.CreatedAt, hook.UpdatedAt = now, now
+	return r.cluster.Writer(ctx).Create(hook).Error
+}
+
+func (r *GormWebhookRepository) Update(ctx context.Context, hook *models.Webhook) error {
+	hook.UpdatedAt = time.Now().UTC()
+	result := r.cluster.Writer(ctx).Model(hook).Select("url", "secret", "events", "description", "active", "updated_at").Updates(hook)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+	return result.Error
+}
+
+func (r *GormWebhookRepository) Delete(ctx context.Context, id int) error {
+	// Sin depender de ON DELETE CASCADE, que SQLite no aplica por defecto
+	return r.cluster.Writer(ctx).Transaction(func(tx *gorm.DB) error {
+		deliveries := tx.Model(&models.WebhookDelivery{}).Select("id").Where("webhook_id = ?", id)
+		if err := tx.Where("delivery_id IN (?)", deliveries).Delete(&models.WebhookAttempt{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.Webhook{}, id)
+		if result.Error == nil && result.RowsAffected == 0 {
+			return ErrWebhookNotFound
+		}
+		return result.Error
+	})
+}
+
+func (r *GormWebhookRepository) AddDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	for i := range deliveries {
+		deliveries[i].CreatedAt, deliveries[i].UpdatedAt = now, now
+	}
+	return r.cluster.Writer(ctx).Create(&deliveries).Error
+}
+
+func (r *GormWebhookRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	tx := r.cluster.Writer(ctx)
+	var candidates []models.WebhookDelivery
+	err := tx.Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+		Order("next_attempt_at").Limit(limit).Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Como en ClaimOrphans, cada una se reserva con un UPDATE condicionado:
+	// si otra instancia la aplazó antes, no cambia ninguna fila
+	var claimed []models.WebhookDelivery
+	until := now.Add(lease)
+	for _, d := range candidates {
+		result := tx.Model(&models.WebhookDelivery{}).
+			Where("id = ? AND status = ? AND next_attempt_at = ?", d.ID, models.DeliveryPending, d.NextAttemptAt).
+			Update("next_attempt_at", until)
+		if result.Error != nil {
+			return claimed, result.Error
+		}
+		if result.RowsAffected > 0 {
+			d.NextAttemptAt = &until
+			claimed = append(claimed, d)
+		}
+	}
+	return claimed, nil
+}
+
+func (r *GormWebhookRepository) RecordAttempt(ctx context.Context, delivery *models.WebhookDelivery, attempt models.WebhookAttempt) error {
+	return r.cluster.Writer(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := saveDelivery(tx, delivery); err != nil {
+			return err
+		}
+		attempt.DeliveryID = delivery.ID
+		return tx.Create(&attempt).Error
+	})
+}
+
+func (r *GormWebhookRepository) SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return saveDelivery(r.cluster.Writer(ctx), delivery)
+}
+
+func saveDelivery(tx *gorm.DB, delivery *models.WebhookDelivery) error {
+	delivery.UpdatedAt = time.Now().UTC()
+	result := tx.Model(delivery).Select("status", "attempts", "next_attempt_at", "last_status", "last_error", "updated_at").Updates(delivery)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrDeliveryNotFound
+	}
+	return result.Error
+}
+
+func (r *GormWebhookRepository) GetDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	tx := r.cluster.Writer(ctx)
+	var delivery models.WebhookDelivery
+	err := tx.First(&delivery, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Where("delivery_id = ?", id).Order("id").Find(&delivery.Log).Error
+	return &delivery, err
+}
+
+func (r *GormWebhookRepository) Deliveries(ctx context.Context, filter DeliveryFilter) ([]models.WebhookDelivery, error) {
+	q := r.cluster.Writer(ctx).Order("id DESC")
+	if filter.WebhookID != 0 {
+		q = q.Where("webhook_id = ?", filter.WebhookID)
+	}
+	if filter.Status != "" {
+		q = q.Where("status = ?", filter.Status)
+	}
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+	var deliveries []models.WebhookDelivery
+	err := q.Find(&deliveries).Error
+	return deliveries, err
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"api3/db"
+	"api3/src/models"
+	"context"
+	"time"
+)
+
+// GormZoneRepository guarda las zonas en la tabla zones
+type GormZoneRepository struct {
+	cluster *db.Cluster
+}
+
+func NewGormZoneRepository(cluster *db.Cluster) *GormZoneRepository {
+	return &GormZoneRepository{cluster: cluster}
+}
+
+func (r *GormZoneRepository) List(ctx context.Context) ([]models.Zone, error) {
+	var zones []models.Zone
+	err := r.cluster.Reader(ctx).Order("name").Find(&zones).Error
+	return zones, err
+}
+
+func (r *GormZoneRepository) Exists(ctx context.Context, name string) (bool, error) {
+	var count int64
+	err := r.cluster.Reader(ctx).Model(&models.Zone{}).Where("name = ?", name).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *GormZoneRepository) Create(ctx context.Context, zone *models.Zone) error {
+	defer r.cluster.MarkWrite(ctx)
+	if zone.CreatedAt.IsZero() {
+		zone.CreatedAt = time.Now().UTC()
+	}
+	return translateError(r.cluster.Writer(ctx).Create(zone).Error)
+}
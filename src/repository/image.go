@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"api3/src/models"
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrImageBusy se devuelve cuando el recolector está borrando la imagen y
+// no se pudo volver a registrar
+var ErrImageBusy = errors.New("la imagen se está eliminando, reintenta")
+
+// ImageRepository lleva la cuenta de referencias de las imágenes
+// direccionadas por contenido
+type ImageRepository interface {
+	// Acquire suma una referencia; created indica que la imagen no existía
+	// y hay que subir sus blobs
+	Acquire(ctx context.Context, image *models.Image) (created bool, err error)
+	// Release resta una referencia; al llegar a 0 la imagen queda huérfana
+	Release(ctx context.Context, hash string) error
+	// ClaimOrphans marca para borrar hasta limit imágenes sin referencias
+	// desde antes de olderThan y devuelve sus hashes
+	ClaimOrphans(ctx context.Context, olderThan time.Time, limit int) ([]string, error)
+	// Forget elimina el registro de una imagen ya marcada y borrada
+	Forget(ctx context.Context, hash string) error
+}
+
+// ref_count de una imagen que el recolector está borrando
+const tombstone = -1
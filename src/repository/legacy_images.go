@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type legacyImage struct {
+	ID    int
+	Image []byte
+}
+
+// MoveImagesToBlobStore guarda con save las imágenes que aún están en la
+// columna users.image, apunta image_key a la clave devuelta y vacía la
+// columna. Procesa de a batchSize filas y se puede relanzar si se interrumpe.
+func MoveImagesToBlobStore(ctx context.Context, db *gorm.DB, save func(ctx context.Context, image []byte) (string, error), batchSize int) (int, error) {
+	moved := 0
+	lastID := 0
+	for {
+		var rows []legacyImage
+		err := db.WithContext(ctx).Table("users").Select("id", "image").
+			Where("id > ? AND image IS NOT NULL", lastID).
+			Order("id").Limit(batchSize).Scan(&rows).Error
+		if err != nil {
+			return moved, err
+		}
+		if len(rows) == 0 {
+			return moved, nil
+		}
+
+		for _, row := range rows {
+			lastID = row.ID
+			if len(row.Image) == 0 {
+				if err := db.WithContext(ctx).Table("users").Where("id = ?", row.ID).Update("image", nil).Error; err != nil {
+					return moved, err
+				}
+				continue
+			}
+
+			key, err := save(ctx, row.Image)
+			if err != nil {
+				return moved, err
+			}
+			err = db.WithContext(ctx).Table("users").Where("id = ?", row.ID).
+				Updates(map[string]interface{}{"image_key": key, "image": nil}).Error
+			if err != nil {
+				return moved, err
+			}
+			moved++
+		}
+	}
+}
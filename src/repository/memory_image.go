@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"api3/src/models"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryImageRepository lleva las referencias en memoria (útil para pruebas)
+type MemoryImageRepository struct {
+	mu     sync.Mutex
+	images map[string]models.Image
+}
+
+func NewMemoryImageRepository() *MemoryImageRepository {
+	return &MemoryImageRepository{images: map[string]models.Image{}}
+}
+
+func (r *MemoryImageRepository) Acquire(ctx context.Context, image *models.Image) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	if existing, ok := r.images[image.Hash]; ok {
+		if existing.RefCount == tombstone {
+			return false, ErrImageBusy
+		}
+		existing.RefCount++
+		existing.UpdatedAt = now
+		r.images[image.Hash] = existing
+		return false, nil
+	}
+
+	row := *image
+	row.RefCount = 1
+	row.CreatedAt, row.UpdatedAt = now, now
+	r.images[image.Hash] = row
+	return true, nil
+}
+
+func (r *MemoryImageRepository) Release(ctx context.Context, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.images[hash]
+	if !ok || existing.RefCount <= 0 {
+		return nil
+	}
+	existing.RefCount--
+	existing.UpdatedAt = time.Now().UTC()
+	r.images[hash] = existing
+	return nil
+}
+
+func (r *MemoryImageRepository) ClaimOrphans(ctx context.Context, olderThan time.Time, limit int) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var claimed []string
+	for hash, image := range r.images {
+		if len(claimed) >= limit {
+			break
+		}
+		if image.RefCount <= 0 && image.UpdatedAt.Before(olderThan) {
+			image.RefCount = tombstone
+			image.UpdatedAt = time.Now().UTC()
+			r.images[hash] = image
+			claimed = append(claimed, hash)
+		}
+	}
+	return claimed, nil
+}
+
+func (r *MemoryImageRepository) Forget(ctx context.Context, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if image, ok := r.images[hash]; ok && image.RefCount == tombstone {
+		delete(r.images, hash)
+	}
+	return nil
+}
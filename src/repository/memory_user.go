@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"api3/src/models"
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryUserRepository guarda los usuarios en memoria (útil para pruebas)
+type MemoryUserRepository struct {
+	mu     sync.RWMutex
+	users  map[int]models.User
+	nextID int
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: map[int]models.User{}, nextID: 1}
+}
+
+func (r *MemoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.usernameTaken(user.Username, 0) {
+		return ErrDuplicate
+	}
+	user.ID = r.nextID
+	r.nextID++
+	if user.Version == 0 {
+		user.Version = 1
+	}
+	user.UpdatedAt = time.Now().UTC()
+	r.users[user.ID] = copyUser(*user)
+	return nil
+}
+
+func (r *MemoryUserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	user = copyUser(user)
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Username == username {
+			user = copyUser(user)
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryUserRepository) List(ctx context.Context, q ListQuery) (*UserPage, error) {
+	order := effectiveSort(q.Sort)
+	backward := q.Cursor != nil && q.Cursor.Backward
+
+	var cursorValues []interface{}
+	if q.Cursor != nil {
+		var err error
+		if cursorValues, err = normalizeCursor(q.Cursor, order); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.RLock()
+	var users []models.User
+	for _, user := range r.users {
+		if matchesFilter(user, q.Filter) {
+			users = append(users, copyUser(user))
+		}
+	}
+	r.mu.RUnlock()
+	total := int64(len(users))
+
+	less := func(a, b []interface{}) bool {
+		c := compareValues(a, b, order)
+		if backward {
+			return c > 0
+		}
+		return c < 0
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return less(sortValues(users[i], order), sortValues(users[j], order))
+	})
+
+	if cursorValues != nil {
+		start := len(users)
+		for i, user := range users {
+			if less(cursorValues, sortValues(user, order)) {
+				start = i
+				break
+			}
+		}
+		users = users[start:]
+	} else if q.Offset > 0 {
+		if q.Offset > len(users) {
+			q.Offset = len(users)
+		}
+		users = users[q.Offset:]
+	}
+	if len(users) > q.Limit+1 {
+		users = users[:q.Limit+1]
+	}
+	return buildPage(users, q, order, total), nil
+}
+
+func (r *MemoryUserRepository) Stream(ctx context.Context, filter UserFilter, sort []SortField, fn func(user *models.User) error) error {
+	r.mu.RLock()
+	count := len(r.users)
+	r.mu.RUnlock()
+
+	// En memoria no hay cursor de BD: se lista todo de una vez
+	page, err := r.List(ctx, ListQuery{Filter: filter, Sort: sort, Limit: count})
+	if err != nil {
+		return err
+	}
+	for i := range page.Users {
+		if err := fn(&page.Users[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MemoryUserRepository) Search(ctx context.Context, terms []string, limit int) ([]models.User, error) {
+	return nil, ErrSearchUnsupported
+}
+
+func (r *MemoryUserRepository) Update(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.users[user.ID]
+	if !ok || current.Version != user.Version {
+		return ErrVersionConflict
+	}
+	if r.usernameTaken(user.Username, user.ID) {
+		return ErrDuplicate
+	}
+	user.Version++
+	user.UpdatedAt = time.Now().UTC()
+	r.users[user.ID] = copyUser(*user)
+	return nil
+}
+
+func (r *MemoryUserRepository) Delete(ctx context.Context, id int, version int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if current, ok := r.users[id]; version > 0 && (!ok || current.Version != version) {
+		return ErrVersionConflict
+	}
+	delete(r.users, id)
+	return nil
+}
+
+// Transaction guarda una copia y la restaura si fn falla. No aísla de otras
+// escrituras concurrentes: basta para pruebas.
+func (r *MemoryUserRepository) Transaction(ctx context.Context, fn func(tx UserRepository) error) error {
+	r.mu.Lock()
+	snapshot := make(map[int]models.User, len(r.users))
+	for id, user := range r.users {
+		snapshot[id] = user
+	}
+	nextID := r.nextID
+	r.mu.Unlock()
+
+	if err := fn(r); err != nil {
+		r.mu.Lock()
+		r.users, r.nextID = snapshot, nextID
+		r.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func matchesFilter(user models.User, f UserFilter) bool {
+	return (f.Role == "" || user.Role == f.Role) &&
+		(f.Zona == "" || user.Zona == f.Zona) &&
+		(f.Status == "" || user.Status == f.Status) &&
+		strings.HasPrefix(user.Username, f.UsernamePrefix)
+}
+
+// Compara dos tuplas de valores de ordenación según el sentido de cada columna
+func compareValues(a, b []interface{}, order []SortField) int {
+	for i, f := range order {
+		c := 0
+		switch av := a[i].(type) {
+		case int:
+			bv := b[i].(int)
+			if av < bv {
+				c = -1
+			} else if av > bv {
+				c = 1
+			}
+		case string:
+			c = strings.Compare(av, b[i].(string))
+		}
+		if f.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// Emula el índice único sobre username; exceptID permite renombrar al mismo usuario
+func (r *MemoryUserRepository) usernameTaken(username string, exceptID int) bool {
+	for id, user := range r.users {
+		if id != exceptID && user.Username == username {
+			return true
+		}
+	}
+	return false
+}
+
+// Evita que quien llama modifique la imagen guardada en el mapa
+func copyUser(user models.User) models.User {
+	if user.Image != nil {
+		user.Image = append([]byte(nil), user.Image...)
+	}
+	return user
+}
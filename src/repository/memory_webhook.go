@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"api3/src/models"
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryWebhookRepository guarda los webhooks en memoria (útil para pruebas)
+type MemoryWebhookRepository struct {
+	mu           sync.Mutex
+	hooks        map[int]models.Webhook
+	deliveries   map[int64]models.WebhookDelivery
+	attempts     map[int64][]models.WebhookAttempt
+	nextHook     int
+	nextDelivery int64
+}
+
+func NewMemoryWebhookRepository() *MemoryWebhookRepository {
+	return &MemoryWebhookRepository{
+		hooks:      map[int]models.Webhook{},
+		deliveries: map[int64]models.WebhookDelivery{},
+		attempts:   map[int64][]models.WebhookAttempt{},
+	}
+}
+
+func (r *MemoryWebhookRepository) List(ctx context.Context) ([]models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hooks := make([]models.Webhook, 0, len(r.hooks))
+	for _, hook := range r.hooks {
+		hooks = append(hooks, hook)
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].ID < hooks[j].ID })
+	return hooks, nil
+}
+
+func (r *MemoryWebhookRepository) Get(ctx context.Context, id int) (*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hook, ok := r.hooks[id]
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+	return &hook, nil
+}
+
+func (r *MemoryWebhookRepository) Create(ctx context.Context, hook *models.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextHook++
+	hook.ID = r.nextHook
+	now := time.Now().UTC()
+	hook.CreatedAt, hook.UpdatedAt = now, now
+	r.hooks[hook.ID] = *hook
+	return nil
+}
+
+func (r *MemoryWebhookRepository) Update(ctx context.Context, hook *models.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.hooks[hook.ID]; !ok {
+		return ErrWebhookNotFound
+	}
+	hook.UpdatedAt = time.Now().UTC()
+	r.hooks[hook.ID] = *hook
+	return nil
+}
+
+func (r *MemoryWebhookRepository) Delete(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.hooks[id]; !ok {
+		return ErrWebhookNotFound
+	}
+	delete(r.hooks, id)
+	for deliveryID, d := range r.deliveries {
+		if d.WebhookID == id {
+			delete(r.deliveries, deliveryID)
+			delete(r.attempts, deliveryID)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryWebhookRepository) AddDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	for i := range deliveries {
+		r.nextDelivery++
+		deliveries[i].ID = r.nextDelivery
+		deliveries[i].CreatedAt, deliveries[i].UpdatedAt = now, now
+		r.deliveries[deliveries[i].ID] = deliveries[i]
+	}
+	return nil
+}
+
+func (r *MemoryWebhookRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []models.WebhookDelivery
+	for _, d := range r.deliveries {
+		if d.Status == models.DeliveryPending && d.NextAttemptAt != nil && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	until := now.Add(lease)
+	for i := range due {
+		due[i].NextAttemptAt = &until
+		r.deliveries[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+func (r *MemoryWebhookRepository) RecordAttempt(ctx context.Context, delivery *models.WebhookDelivery, attempt models.WebhookAttempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.save(delivery); err != nil {
+		return err
+	}
+	attempt.DeliveryID = delivery.ID
+	attempt.ID = int64(len(r.attempts[delivery.ID]) + 1)
+	r.attempts[delivery.ID] = append(r.attempts[delivery.ID], attempt)
+	return nil
+}
+
+func (r *MemoryWebhookRepository) SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.save(delivery)
+}
+
+func (r *MemoryWebhookRepository) save(delivery *models.WebhookDelivery) error {
+	if _, ok := r.deliveries[delivery.ID]; !ok {
+		return ErrDeliveryNotFound
+	}
+	delivery.UpdatedAt = time.Now().UTC()
+	stored := *delivery
+	stored.Log = nil
+	r.deliveries[delivery.ID] = stored
+	return nil
+}
+
+func (r *MemoryWebhookRepository) GetDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delivery, ok := r.deliveries[id]
+	if !ok {
+		return nil, ErrDeliveryNotFound
+	}
+	delivery.Log = append([]models.WebhookAttempt(nil), r.attempts[id]...)
+	return &delivery, nil
+}
+
+func (r *MemoryWebhookRepository) Deliveries(ctx context.Context, filter DeliveryFilter) ([]models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deliveries []models.WebhookDelivery
+	for _, d := range r.deliveries {
+		if (filter.WebhookID == 0 || d.WebhookID == filter.WebhookID) && (filter.Status == "" || d.Status == filter.Status) {
+			deliveries = append(deliveries, d)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID > deliveries[j].ID })
+	if filter.Limit > 0 && len(deliveries) > filter.Limit {
+		deliveries = deliveries[:filter.Limit]
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"api3/src/models"
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryZoneRepository guarda las zonas en memoria (útil para pruebas)
+type MemoryZoneRepository struct {
+	mu    sync.RWMutex
+	zones map[string]models.Zone
+}
+
+func NewMemoryZoneRepository() *MemoryZoneRepository {
+	return &MemoryZoneRepository{zones: map[string]models.Zone{}}
+}
+
+func (r *MemoryZoneRepository) List(ctx context.Context) ([]models.Zone, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	zones := make([]models.Zone, 0, len(r.zones))
+	for _, zone := range r.zones {
+		zones = append(zones, zone)
+	}
+	sort.Slice(zones, func(i, j int) bool { return zones[i].Name < zones[j].Name })
+	return zones, nil
+}
+
+func (r *MemoryZoneRepository) Exists(ctx context.Context, name string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.zones[name]
+	return ok, nil
+}
+
+func (r *MemoryZoneRepository) Create(ctx context.Context, zone *models.Zone) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.zones[zone.Name]; ok {
+		return ErrDuplicate
+	}
+	if zone.CreatedAt.IsZero() {
+		zone.CreatedAt = time.Now().UTC()
+	}
+	r.zones[zone.Name] = *zone
+	return nil
+}
@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"api3/src/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidCursor se devuelve cuando el cursor no se puede decodificar o
+// no corresponde al orden pedido
+var ErrInvalidCursor = errors.New("cursor inválido")
+
+// Columnas por las que se puede ordenar el listado
+var sortableColumns = map[string]bool{
+	"id":       true,
+	"username": true,
+	"role":     true,
+	"zona":     true,
+	"status":   true,
+}
+
+// UserFilter restringe el listado; los campos vacíos no filtran
+type UserFilter struct {
+	Role           string
+	Zona           string
+	Status         string
+	UsernamePrefix string
+}
+
+// SortField es una columna de ordenación
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// ListQuery describe una página del listado. Si Cursor no es nil se pagina
+// por cursor (keyset) y Offset se ignora.
+type ListQuery struct {
+	Filter UserFilter
+	Sort   []SortField
+	Limit  int
+	Offset int
+	Cursor *Cursor
+}
+
+// UserPage es una página del listado con los cursores para moverse
+type UserPage struct {
+	Users []models.User
+	Total int64
+	// Cursores de la página siguiente y anterior (nil si no hay)
+	Next *Cursor
+	Prev *Cursor
+}
+
+// Cursor guarda los valores de ordenación de la última (o primera) fila
+// vista. Backward indica que se pide la página anterior.
+type Cursor struct {
+	Values   []interface{} `json:"v"`
+	Backward bool          `json:"b,omitempty"`
+}
+
+// SortableColumns devuelve las columnas por las que se puede ordenar
+func SortableColumns() []string {
+	columns := make([]string, 0, len(sortableColumns))
+	for column := range sortableColumns {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// ParseSort interpreta "username,-id": un "-" delante ordena descendente
+func ParseSort(raw string) ([]SortField, error) {
+	var fields []SortField
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field := SortField{Column: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if !sortableColumns[field.Column] {
+			return nil, fmt.Errorf("no se puede ordenar por %q", field.Column)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// Encode serializa el cursor para usarlo en una URL
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor es la inversa de Encode
+func DecodeCursor(raw string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || len(c.Values) == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// Orden efectivo: el pedido más id como desempate para que el cursor sea único
+func effectiveSort(sort []SortField) []SortField {
+	for _, f := range sort {
+		if f.Column == "id" {
+			return sort
+		}
+	}
+	return append(append([]SortField(nil), sort...), SortField{Column: "id"})
+}
+
+// Valores de las columnas de ordenación de un usuario, en el orden dado
+func sortValues(user models.User, sort []SortField) []interface{} {
+	values := make([]interface{}, len(sort))
+	for i, f := range sort {
+		values[i] = columnValue(user, f.Column)
+	}
+	return values
+}
+
+func columnValue(user models.User, column string) interface{} {
+	switch column {
+	case "id":
+		return user.ID
+	case "username":
+		return user.Username
+	case "role":
+		return user.Role
+	case "zona":
+		return user.Zona
+	case "status":
+		return user.Status
+	}
+	return nil
+}
+
+// Comprueba que el cursor encaje con el orden y normaliza los números
+// (JSON los decodifica como float64)
+func normalizeCursor(c *Cursor, sort []SortField) ([]interface{}, error) {
+	if len(c.Values) != len(sort) {
+		return nil, ErrInvalidCursor
+	}
+	values := make([]interface{}, len(sort))
+	for i, f := range sort {
+		switch v := c.Values[i].(type) {
+		case float64:
+			if f.Column != "id" {
+				return nil, ErrInvalidCursor
+			}
+			values[i] = int(v)
+		case int:
+			values[i] = v
+		case string:
+			if f.Column == "id" {
+				return nil, ErrInvalidCursor
+			}
+			values[i] = v
+		default:
+			return nil, ErrInvalidCursor
+		}
+	}
+	return values, nil
+}
+
+// Arma la página a partir de las filas leídas (hasta limit+1) en el sentido
+// de la consulta. Las filas de una consulta hacia atrás llegan invertidas.
+func buildPage(rows []models.User, q ListQuery, sort []SortField, total int64) *UserPage {
+	page := &UserPage{Total: total}
+	backward := q.Cursor != nil && q.Cursor.Backward
+	hasMore := len(rows) > q.Limit
+	if hasMore {
+		rows = rows[:q.Limit]
+	}
+	if backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	page.Users = rows
+	if len(rows) == 0 {
+		return page
+	}
+
+	first := &Cursor{Values: sortValues(rows[0], sort), Backward: true}
+	last := &Cursor{Values: sortValues(rows[len(rows)-1], sort)}
+	switch {
+	case q.Cursor == nil:
+		if hasMore {
+			page.Next = last
+		}
+	case backward:
+		page.Next = last
+		if hasMore {
+			page.Prev = first
+		}
+	default:
+		page.Prev = first
+		if hasMore {
+			page.Next = last
+		}
+	}
+	return page
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"api3/src/models"
+	"context"
+	"errors"
+)
+
+// ErrNotFound se devuelve cuando el usuario solicitado no existe
+var ErrNotFound = errors.New("usuario no encontrado")
+
+// ErrDuplicate se devuelve cuando el nombre de usuario ya está en uso
+var ErrDuplicate = errors.New("el nombre de usuario ya existe")
+
+// ErrVersionConflict se devuelve cuando el usuario ya no tiene la versión
+// esperada: otro cambio se guardó antes o se borró
+var ErrVersionConflict = errors.New("el usuario cambió desde que se leyó")
+
+// ErrSearchUnsupported se devuelve cuando la BD no tiene índice de texto
+// completo; la búsqueda usa entonces un índice en memoria
+var ErrSearchUnsupported = errors.New("la base de datos no soporta búsqueda de texto completo")
+
+// UserRepository abstrae el acceso a los usuarios para que los
+// controladores no dependan de una base de datos concreta
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id int) (*models.User, error)
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	List(ctx context.Context, q ListQuery) (*UserPage, error)
+	// Stream recorre en orden todos los usuarios del filtro sin cargarlos
+	// a la vez; se detiene en el primer error de fn
+	Stream(ctx context.Context, filter UserFilter, sort []SortField, fn func(user *models.User) error) error
+	// Search busca con el índice de texto completo los usuarios cuyo
+	// username, display_name o zona contienen todos los términos (por
+	// prefijo), los más relevantes primero
+	Search(ctx context.Context, terms []string, limit int) ([]models.User, error)
+	// Update guarda el usuario solo si sigue en user.Version y la incrementa
+	Update(ctx context.Context, user *models.User) error
+	// Delete borra el usuario; con version > 0 solo si sigue en esa versión
+	Delete(ctx context.Context, id int, version int64) error
+	// Transaction ejecuta fn con un repositorio cuyas escrituras se
+	// confirman juntas; si fn devuelve error se deshacen todas
+	Transaction(ctx context.Context, fn func(tx UserRepository) error) error
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"api3/db"
+	"api3/src/models"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Abre una BD SQLite en memoria con todas las migraciones aplicadas
+func openSQLite(t *testing.T) *db.Cluster {
+	t.Helper()
+	gormDB, err := db.Open(db.Config{Driver: db.DriverSQLite, DSN: fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name()), MaxIdleConns: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := gormDB.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	migrator, err := db.NewMigrator(sqlDB, db.DriverSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db.NewCluster(gormDB, 0)
+}
+
+// Las dos implementaciones deben comportarse igual
+func TestGormUserRepository(t *testing.T) {
+	testUserRepository(t, NewGormUserRepository(openSQLite(t)))
+}
+
+func TestMemoryUserRepository(t *testing.T) {
+	testUserRepository(t, NewMemoryUserRepository())
+}
+
+func testUserRepository(t *testing.T, repo UserRepository) {
+	ctx := context.Background()
+	users := map[string]*models.User{}
+	for _, u := range []struct{ name, role, zona string }{{"ana", models.RoleAdmin, "norte"}, {"bea", models.RoleUser, "sur"}, {"carla", models.RoleUser, "norte"}} {
+		user := &models.User{Username: u.name, Password: "x", Role: u.role, Zona: u.zona, Status: models.StatusActive}
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatalf("crear %s: %v", u.name, err)
+		}
+		if user.ID == 0 || user.Version != 1 {
+			t.Fatalf("%s creado con ID %d y versión %d", u.name, user.ID, user.Version)
+		}
+		users[u.name] = user
+	}
+
+	t.Run("lecturas", func(t *testing.T) {
+		if err := repo.Create(ctx, &models.User{Username: "ana", Zona: "sur", Status: models.StatusActive}); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("usuario repetido: %v", err)
+		}
+		if got, err := repo.GetByUsername(ctx, "bea"); err != nil || got.ID != users["bea"].ID || got.Zona != "sur" {
+			t.Errorf("GetByUsername: %+v %v", got, err)
+		}
+		if _, err := repo.GetByID(ctx, 9999); !errors.Is(err, ErrNotFound) {
+			t.Errorf("GetByID inexistente: %v", err)
+		}
+		if _, err := repo.GetByUsername(ctx, "nadie"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("GetByUsername inexistente: %v", err)
+		}
+	})
+
+	t.Run("listado", func(t *testing.T) {
+		for _, tt := range []struct {
+			name  string
+			q     ListQuery
+			total int64
+			want  string
+		}{
+			{"todos", ListQuery{Limit: 10}, 3, "ana,bea,carla"},
+			{"filtro y orden", ListQuery{Filter: UserFilter{Zona: "norte"}, Sort: []SortField{{Column: "username", Desc: true}}, Limit: 10}, 2, "carla,ana"},
+			{"prefijo", ListQuery{Filter: UserFilter{UsernamePrefix: "b"}, Limit: 10}, 1, "bea"},
+			{"offset", ListQuery{Limit: 1, Offset: 2}, 3, "carla"},
+		} {
+			page, err := repo.List(ctx, tt.q)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if got := usernames(page.Users); page.Total != tt.total || got != tt.want {
+				t.Errorf("%s: %d %s, se esperaba %d %s", tt.name, page.Total, got, tt.total, tt.want)
+			}
+		}
+	})
+
+	t.Run("cursor", func(t *testing.T) {
+		// Por zona y luego por id, recorriendo de uno en uno como un cliente
+		q := ListQuery{Sort: []SortField{{Column: "zona"}}, Limit: 1}
+		var seen []string
+		for i := 0; i < 5; i++ {
+			page, err := repo.List(ctx, q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			seen = append(seen, usernames(page.Users))
+			if page.Next == nil {
+				break
+			}
+			if q.Cursor, err = DecodeCursor(page.Next.Encode()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := strings.Join(seen, ","); got != "ana,carla,bea" {
+			t.Errorf("páginas %s, se esperaba ana,carla,bea", got)
+		}
+	})
+
+	t.Run("versiones", func(t *testing.T) {
+		stale := *users["bea"]
+		bea := *users["bea"]
+		bea.Zona = "norte"
+		if err := repo.Update(ctx, &bea); err != nil || bea.Version != 2 {
+			t.Fatalf("actualizar: versión %d, %v", bea.Version, err)
+		}
+		stale.Zona = "sur"
+		if err := repo.Update(ctx, &stale); !errors.Is(err, ErrVersionConflict) {
+			t.Errorf("actualizar con versión vieja: %v", err)
+		}
+		bea.Username = "ana"
+		if err := repo.Update(ctx, &bea); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("renombrar a un nombre en uso: %v", err)
+		}
+		if err := repo.Delete(ctx, bea.ID, 1); !errors.Is(err, ErrVersionConflict) {
+			t.Errorf("borrar con versión vieja: %v", err)
+		}
+		if err := repo.Delete(ctx, bea.ID, 2); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.GetByID(ctx, bea.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("tras borrar: %v", err)
+		}
+	})
+
+	t.Run("transacción", func(t *testing.T) {
+		rollback := errors.New("deshacer")
+		err := repo.Transaction(ctx, func(tx UserRepository) error {
+			if err := tx.Create(ctx, &models.User{Username: "dora", Zona: "sur", Status: models.StatusActive}); err != nil {
+				return err
+			}
+			if _, err := tx.GetByUsername(ctx, "dora"); err != nil {
+				t.Errorf("dentro de la transacción: %v", err)
+			}
+			return rollback
+		})
+		if !errors.Is(err, rollback) {
+			t.Fatalf("Transaction devolvió %v", err)
+		}
+		if _, err := repo.GetByUsername(ctx, "dora"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("la transacción deshecha dejó el usuario: %v", err)
+		}
+	})
+}
+
+func usernames(users []models.User) string {
+	names := make([]string, len(users))
+	for i, u := range users {
+		names[i] = u.Username
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"api3/src/models"
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrWebhookNotFound se devuelve cuando el webhook no existe
+var ErrWebhookNotFound = errors.New("webhook no encontrado")
+
+// ErrDeliveryNotFound se devuelve cuando la entrega no existe
+var ErrDeliveryNotFound = errors.New("entrega no encontrada")
+
+// WebhookRepository guarda las suscripciones, sus entregas y el registro de
+// intentos
+type WebhookRepository interface {
+	List(ctx context.Context) ([]models.Webhook, error)
+	Get(ctx context.Context, id int) (*models.Webhook, error)
+	Create(ctx context.Context, hook *models.Webhook) error
+	Update(ctx context.Context, hook *models.Webhook) error
+	// Delete borra el webhook con sus entregas
+	Delete(ctx context.Context, id int) error
+
+	// AddDeliveries guarda entregas nuevas (les asigna el ID)
+	AddDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error
+	// ClaimDue reserva hasta limit entregas pendientes cuyo turno llegó antes
+	// de now: las aplaza hasta now+lease para que otra instancia no las envíe
+	// a la vez
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	// RecordAttempt guarda el estado de la entrega tras un intento y lo
+	// añade al registro
+	RecordAttempt(ctx context.Context, delivery *models.WebhookDelivery, attempt models.WebhookAttempt) error
+	// SaveDelivery guarda el estado de la entrega (p. ej. al reenviarla)
+	SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	// GetDelivery devuelve la entrega con sus intentos en Log
+	GetDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error)
+	// Deliveries lista las entregas, de la más nueva a la más antigua
+	Deliveries(ctx context.Context, filter DeliveryFilter) ([]models.WebhookDelivery, error)
+}
+
+// DeliveryFilter selecciona entregas; los campos vacíos no filtran
+type DeliveryFilter struct {
+	WebhookID int
+	Status    string
+	Limit     int
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"api3/src/models"
+	"context"
+)
+
+// ZoneRepository guarda las zonas válidas para los usuarios. Create devuelve
+// ErrDuplicate si la zona ya existe.
+type ZoneRepository interface {
+	List(ctx context.Context) ([]models.Zone, error)
+	Exists(ctx context.Context, name string) (bool, error)
+	Create(ctx context.Context, zone *models.Zone) error
+}
+
+// EnsureZones crea las zonas que falten (para preparar una BD nueva)
+func EnsureZones(ctx context.Context, zones ZoneRepository, names []string) error {
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if err := zones.Create(ctx, &models.Zone{Name: name}); err != nil && err != ErrDuplicate {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"api3/src/models"
+	"api3/src/openapi"
+	"api3/src/utils"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// api registra cada ruta en el router y su operación en la especificación
+// OpenAPI, así la documentación sale de las mismas llamadas que las rutas
+type api struct {
+	router *mux.Router
+	prefix string
+	spec   *openapi.Spec
+}
+
+// handle aplica los middlewares que declara op (autenticación, If-Match,
+// obsolescencia) y registra la ruta
+func (a api) handle(method, path string, handler http.HandlerFunc, op openapi.Operation) {
+	if op.IfMatch {
+		handler = utils.RequireIfMatch(handler)
+	}
+	switch op.Auth {
+	case openapi.Admin:
+		handler = utils.RequireRole(models.RoleAdmin)(handler)
+	case openapi.Authenticated:
+		handler = utils.RequireAuth(handler)
+	case openapi.Optional:
+		handler = utils.OptionalAuth(handler)
+	}
+	if op.Successor != "" {
+		handler = deprecated(op.Successor, handler)
+	}
+
+	a.router.HandleFunc(path, handler).Methods(method)
+	a.spec.Add(method, a.prefix+path, op)
+}
@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Fechas por defecto de las rutas obsoletas. LEGACY_SUNSET (AAAA-MM-DD)
+// permite mover la fecha de retirada sin recompilar.
+var (
+	legacyDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	legacySunset       = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+func sunsetDate() time.Time {
+	if raw := os.Getenv("LEGACY_SUNSET"); raw != "" {
+		if t, err := time.Parse("2006-01-02", raw); err == nil {
+			return t
+		}
+	}
+	return legacySunset
+}
+
+// deprecated marca una ruta anterior con las cabeceras Deprecation (RFC 9745)
+// y Sunset (RFC 8594), y enlaza la ruta que la sustituye. En successor las
+// variables {nombre} se reemplazan por las de la petición.
+func deprecated(successor string, next http.HandlerFunc) http.HandlerFunc {
+	sunset := sunsetDate()
+	return func(w http.ResponseWriter, r *http.Request) {
+		link := successor
+		for name, value := range mux.Vars(r) {
+			link = strings.ReplaceAll(link, "{"+name+"}", value)
+		}
+
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", legacyDeprecatedAt.Unix()))
+		w.Header().Set("Sunset", sunset.Format(http.TimeFormat))
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, link))
+		next(w, r)
+	}
+}
@@ -0,0 +1,450 @@
+package routes
+
+import (
+	"api3/src/controllers"
+	"api3/src/events"
+	"api3/src/export"
+	"api3/src/gql"
+	"api3/src/images"
+	"api3/src/models"
+	"api3/src/openapi"
+	"api3/src/webhooks"
+	"net/http"
+)
+
+// Contrato de cada ruta, en el orden de SetupRoutes. Lo que dependa de los
+// middlewares (token, rol admin, If-Match, rutas obsoletas) lo completa
+// openapi.Spec.Add a partir de Auth, IfMatch y Successor.
+
+var imageParam = openapi.Param{Name: "image", Description: "inline para incluir la imagen en base64 en vez de imageUrl", Enum: []string{"inline"}}
+
+// Filtros y orden comunes al listado y a la exportación
+var filterParams = []openapi.Param{
+	{Name: "role", Description: "Filtrar por rol"},
+	{Name: "zona", Description: "Filtrar por zona"},
+	{Name: "status", Description: "Filtrar por estado", Enum: []string{models.StatusActive, models.StatusInactive, models.StatusSuspended}},
+	{Name: "username", Description: "Filtrar por prefijo del nombre de usuario"},
+	{Name: "sort", Description: "Columnas separadas por coma: id, username, role, zona, status; '-' para descendente"},
+}
+
+var deliveryLimitParam = openapi.Param{Name: "limit", Type: "integer", Description: "Máximo de entregas (por defecto 50, máximo 500)"}
+
+var (
+	opLogin = openapi.Operation{
+		ID:          "login",
+		Summary:     "Iniciar sesión",
+		Description: "Autentica un usuario y devuelve un token JWT para el parámetro token.",
+		Tags:        []string{"auth"},
+		Params:      []openapi.Param{imageParam},
+		Body:        &openapi.Body{Schema: controllers.LoginInput{}},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Token y datos del usuario", Schema: controllers.LoginResponse{}},
+			{Status: http.StatusUnauthorized, Description: "Credenciales inválidas"},
+			{Status: http.StatusUnprocessableEntity, Description: "Errores por campo"},
+		},
+	}
+	opAcceptInvitation = openapi.Operation{
+		ID:          "acceptInvitation",
+		Summary:     "Aceptar invitación",
+		Description: "Canjea el token de invitación de una importación por una contraseña y activa al usuario. El token deja de servir en cuanto el usuario cambia.",
+		Tags:        []string{"auth"},
+		Body:        &openapi.Body{Schema: controllers.AcceptInvitationInput{}},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Invitación aceptada", ContentType: []string{"text/plain"}, Headers: []string{"ETag"}},
+			{Status: http.StatusGone, Description: "Invitación inválida, caducada o ya usada"},
+			{Status: http.StatusUnprocessableEntity, Description: "Errores por campo"},
+		},
+	}
+	opListUsers = openapi.Operation{
+		ID:          "listUsers",
+		Summary:     "Listar usuarios",
+		Description: "Devuelve una página de usuarios. Pagina por offset o por cursor; X-Total-Count lleva el total filtrado.",
+		Tags:        []string{"users"},
+		Auth:        openapi.Admin,
+		Params: append([]openapi.Param{
+			{Name: "limit", Type: "integer", Description: "Tamaño de página (por defecto 50, máximo 200)"},
+			{Name: "offset", Type: "integer", Description: "Filas a saltar (paginación por offset)"},
+			{Name: "cursor", Description: "Cursor de next/prev (paginación por cursor)"},
+			imageParam,
+		}, filterParams...),
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Página de usuarios", Schema: controllers.UserListResponse{}, Headers: []string{"X-Total-Count"}},
+			{Status: http.StatusBadRequest, Description: "Parámetros inválidos"},
+		},
+	}
+	opRegisterUser = openapi.Operation{
+		ID:          "registerUser",
+		Summary:     "Registrar usuario",
+		Description: "Crea un usuario. Es público, salvo crear un admin (role=admin), que requiere un token de admin. La imagen de perfil va en base64 (JSON o formulario) o como archivo (multipart).",
+		Tags:        []string{"users"},
+		Auth:        openapi.Optional,
+		Body:        &openapi.Body{Schema: controllers.RegisterInput{}, Files: []string{"image"}},
+		Responses: []openapi.Response{
+			{Status: http.StatusCreated, Description: "Usuario creado", ContentType: []string{"text/plain"}, Headers: []string{"Location", "ETag"}},
+			{Status: http.StatusBadRequest, Description: "Cuerpo inválido"},
+			{Status: http.StatusForbidden, Description: "Crear un admin requiere rol admin"},
+			{Status: http.StatusConflict, Description: "El usuario ya existe"},
+			{Status: http.StatusUnprocessableEntity, Description: "Errores por campo"},
+		},
+	}
+	opImportUsers = openapi.Operation{
+		ID:          "importUsers",
+		Summary:     "Importar usuarios",
+		Description: "Crea o actualiza (por username) usuarios desde CSV o JSON Lines con columnas username, displayName, password, role, zona y status. Las filas sin password reciben una contraseña generada o una invitación. Devuelve el resultado de cada fila.",
+		Tags:        []string{"users"},
+		Auth:        openapi.Admin,
+		Params: []openapi.Param{
+			{Name: "format", Description: "Por defecto según el Content-Type o la extensión del archivo", Enum: []string{"csv", "jsonl"}},
+			{Name: "dry_run", Type: "boolean", Description: "Valida y simula sin guardar nada"},
+			{Name: "atomic", Type: "boolean", Description: "Todo o nada: si una fila falla no se guarda ninguna"},
+			{Name: "chunk", Type: "integer", Description: "Filas por transacción (por defecto 100, máximo 1000)"},
+			{Name: "passwords", Description: "Qué hacer con las filas sin password (por defecto generate)", Enum: []string{"generate", "invite"}},
+		},
+		Body: &openapi.Body{Raw: []string{"text/csv", "application/x-ndjson"}, Files: []string{"file"}},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Resultado de cada fila", Schema: controllers.ImportReport{}},
+			{Status: http.StatusBadRequest, Description: "Archivo o parámetros inválidos"},
+			{Status: http.StatusUnsupportedMediaType, Description: "Formato no soportado"},
+			{Status: http.StatusUnprocessableEntity, Description: "Con atomic, alguna fila falló y no se guardó nada", Schema: controllers.ImportReport{}},
+		},
+	}
+	opExportUsers = openapi.Operation{
+		ID:          "exportUsers",
+		Summary:     "Exportar usuarios",
+		Description: "Descarga los usuarios filtrados como CSV, JSON Lines o XLSX. Las filas se envían a medida que se leen; nunca incluye contraseñas ni imágenes.",
+		Tags:        []string{"users"},
+		Auth:        openapi.Admin,
+		Params: append([]openapi.Param{
+			{Name: "format", Description: "Formato del archivo (por defecto csv)", Enum: export.Formats()},
+			{Name: "columns", Description: "Columnas separadas por coma: id, username, displayName, role, zona, status, version, updatedAt (por defecto todas)"},
+		}, filterParams...),
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Archivo exportado", ContentType: []string{export.ContentType(export.FormatCSV), export.ContentType(export.FormatJSONL), export.ContentType(export.FormatXLSX)}, Headers: []string{"Content-Disposition"}},
+			{Status: http.StatusBadRequest, Description: "Parámetros inválidos"},
+		},
+	}
+	opSearchUsers = openapi.Operation{
+		ID:          "searchUsers",
+		Summary:     "Buscar usuarios",
+		Description: "Busca por prefijo y de forma aproximada (tolera errores de tipeo) en username, displayName y zona. Los resultados vienen ordenados por relevancia y con las coincidencias resaltadas.",
+		Tags:        []string{"users"},
+		Auth:        openapi.Admin,
+		Params: []openapi.Param{
+			{Name: "q", Description: "Texto a buscar", Required: true},
+			{Name: "limit", Type: "integer", Description: "Máximo de resultados (por defecto 20, máximo 100)"},
+			imageParam,
+		},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Usuarios encontrados", Schema: controllers.SearchResponse{}},
+			{Status: http.StatusBadRequest, Description: "Parámetros inválidos"},
+		},
+	}
+	opGetUserByUsername = openapi.Operation{
+		ID:        "getUserByUsername",
+		Summary:   "Obtener un usuario por nombre",
+		Tags:      []string{"users"},
+		Auth:      openapi.Admin,
+		Params:    []openapi.Param{imageParam, ifNoneMatchParam},
+		Responses: userResponses,
+	}
+	opGetUser = openapi.Operation{
+		ID:        "getUser",
+		Summary:   "Obtener un usuario",
+		Tags:      []string{"users"},
+		Auth:      openapi.Admin,
+		Params:    []openapi.Param{imageParam, ifNoneMatchParam},
+		Responses: userResponses,
+	}
+	opReplaceUser = openapi.Operation{
+		ID:          "replaceUser",
+		Summary:     "Reemplazar usuario",
+		Description: "Reemplaza el usuario completo. Si falta status vale active, si falta displayName queda vacío y si falta image se quita la imagen. La contraseña solo cambia si se envía.",
+		Tags:        []string{"users"},
+		Auth:        openapi.Admin,
+		IfMatch:     true,
+		Body:        &openapi.Body{Schema: controllers.ReplaceUserInput{}, Files: []string{"image"}},
+		Responses:   updateResponses,
+	}
+	opPatchUser = openapi.Operation{
+		ID:          "patchUser",
+		Summary:     "Modificar usuario parcialmente",
+		Description: `Aplica un JSON Merge Patch (RFC 7386) o un JSON Patch (RFC 6902) al usuario. "image" lleva la imagen en base64; con merge patch, "image": null la quita.`,
+		Tags:        []string{"users"},
+		Auth:        openapi.Admin,
+		IfMatch:     true,
+		Body:        &openapi.Body{Schema: controllers.UserPatch{}, MergePatch: true},
+		Responses: append([]openapi.Response{
+			{Status: http.StatusConflict, Description: "El parche no se puede aplicar"},
+			{Status: http.StatusUnsupportedMediaType, Description: "Tipo de contenido no soportado"},
+		}, updateResponses...),
+	}
+	opDeleteUser = openapi.Operation{
+		ID:          "deleteUser",
+		Summary:     "Eliminar usuario",
+		Description: "Elimina el usuario y libera su imagen.",
+		Tags:        []string{"users"},
+		Auth:        openapi.Admin,
+		IfMatch:     true,
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Usuario eliminado", ContentType: []string{"text/plain"}},
+			{Status: http.StatusNotFound, Description: "Usuario no encontrado"},
+		},
+	}
+	opGetUserImage = openapi.Operation{
+		ID:          "getUserImage",
+		Summary:     "Imagen de perfil",
+		Description: "Devuelve la imagen del usuario con su Content-Type. Soporta ETag/If-None-Match y peticiones Range.",
+		Tags:        []string{"users"},
+		Auth:        openapi.Authenticated,
+		Params: []openapi.Param{
+			{Name: "size", Description: "Tamaño (por defecto original)", Enum: []string{images.SizeSmall, images.SizeMedium, images.SizeOriginal}},
+			{Name: "v", Description: "Versión de la imagen (la incluye imageUrl); permite cachearla indefinidamente"},
+			ifNoneMatchParam,
+			{Name: "Range", In: "header", Description: "Rango de bytes"},
+		},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Imagen", ContentType: []string{"image/*"}, Headers: []string{"ETag", "Accept-Ranges"}},
+			{Status: http.StatusPartialContent, Description: "Rango parcial de la imagen", ContentType: []string{"image/*"}, Headers: []string{"ETag", "Content-Range"}},
+			{Status: http.StatusNotModified, Description: "No modificada", Headers: []string{"ETag"}},
+			{Status: http.StatusBadRequest, Description: "Tamaño inválido"},
+			{Status: http.StatusNotFound, Description: "Usuario o imagen no encontrados"},
+		},
+	}
+	opListZones = openapi.Operation{
+		ID:          "listZones",
+		Summary:     "Listar zonas",
+		Description: "Devuelve las zonas válidas para el campo zona de los usuarios.",
+		Tags:        []string{"zones"},
+		Auth:        openapi.Authenticated,
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Zonas", Schema: []models.Zone{}},
+		},
+	}
+	opCreateZone = openapi.Operation{
+		ID:          "createZone",
+		Summary:     "Crear zona",
+		Description: "Añade una zona al catálogo.",
+		Tags:        []string{"zones"},
+		Auth:        openapi.Admin,
+		Body:        &openapi.Body{Schema: controllers.ZoneInput{}},
+		Responses: []openapi.Response{
+			{Status: http.StatusCreated, Description: "Zona creada", Schema: models.Zone{}},
+			{Status: http.StatusConflict, Description: "La zona ya existe"},
+			{Status: http.StatusUnprocessableEntity, Description: "Nombre inválido"},
+		},
+	}
+	opListWebhooks = openapi.Operation{
+		ID:          "listWebhooks",
+		Summary:     "Listar webhooks",
+		Description: "Devuelve los sistemas suscritos a los cambios de usuarios. Los secretos no se muestran.",
+		Tags:        []string{"webhooks"},
+		Auth:        openapi.Admin,
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Webhooks", Schema: []models.Webhook{}},
+		},
+	}
+	opCreateWebhook = openapi.Operation{
+		ID:          "createWebhook",
+		Summary:     "Crear webhook",
+		Description: "Suscribe una URL a los eventos indicados (todos si events está vacío). Cada entrega es un POST con el evento en JSON, firmado en " + webhooks.HeaderSignature + " como t=<unix>,v1=<HMAC-SHA256 en hex de \"<t>.<cuerpo>\">. El secreto solo se devuelve aquí y al rotarlo.",
+		Tags:        []string{"webhooks"},
+		Auth:        openapi.Admin,
+		Body:        &openapi.Body{Schema: controllers.WebhookInput{}, JSONOnly: true},
+		Responses: []openapi.Response{
+			{Status: http.StatusCreated, Description: "Webhook creado con su secreto", Schema: controllers.WebhookSecret{}, Headers: []string{"Location"}},
+			{Status: http.StatusUnprocessableEntity, Description: "Errores por campo"},
+		},
+	}
+	opListDeadLetters = openapi.Operation{
+		ID:          "listDeadLetters",
+		Summary:     "Entregas fallidas",
+		Description: "Devuelve las entregas de todos los webhooks que agotaron sus reintentos, de la más reciente a la más antigua.",
+		Tags:        []string{"webhooks"},
+		Auth:        openapi.Admin,
+		Params:      []openapi.Param{deliveryLimitParam},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Entregas fallidas", Schema: []models.WebhookDelivery{}},
+			{Status: http.StatusBadRequest, Description: "Límite inválido"},
+		},
+	}
+	opGetDelivery = openapi.Operation{
+		ID:          "getWebhookDelivery",
+		Summary:     "Obtener entrega",
+		Description: "Devuelve una entrega con el registro de sus intentos.",
+		Tags:        []string{"webhooks"},
+		Auth:        openapi.Admin,
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Entrega", Schema: models.WebhookDelivery{}},
+			{Status: http.StatusNotFound, Description: "Entrega no encontrada"},
+		},
+	}
+	opRedeliver = openapi.Operation{
+		ID:          "redeliverWebhook",
+		Summary:     "Reenviar entrega",
+		Description: "Vuelve a poner la entrega en cola con todos sus reintentos; el registro conserva los intentos anteriores.",
+		Tags:        []string{"webhooks"},
+		Auth:        openapi.Admin,
+		Responses: []openapi.Response{
+			{Status: http.StatusAccepted, Description: "Entrega en cola", Schema: models.WebhookDelivery{}},
+			{Status: http.StatusNotFound, Description: "Entrega no encontrada"},
+		},
+	}
+	opGetWebhook = openapi.Operation{
+		ID:      "getWebhook",
+		Summary: "Obtener webhook",
+		Tags:    []string{"webhooks"},
+		Auth:    openapi.Admin,
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Webhook", Schema: models.Webhook{}},
+			{Status: http.StatusNotFound, Description: "Webhook no encontrado"},
+		},
+	}
+	opUpdateWebhook = openapi.Operation{
+		ID:          "updateWebhook",
+		Summary:     "Modificar webhook",
+		Description: "Cambia los campos presentes. Con rotateSecret genera un secreto nuevo y lo devuelve.",
+		Tags:        []string{"webhooks"},
+		Auth:        openapi.Admin,
+		Body:        &openapi.Body{Schema: controllers.WebhookPatch{}, JSONOnly: true},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Webhook modificado (con secret si se rotó)", Schema: controllers.WebhookSecret{}},
+			{Status: http.StatusNotFound, Description: "Webhook no encontrado"},
+			{Status: http.StatusUnprocessableEntity, Description: "Errores por campo"},
+		},
+	}
+	opDeleteWebhook = openapi.Operation{
+		ID:          "deleteWebhook",
+		Summary:     "Eliminar webhook",
+		Description: "Elimina el webhook con sus entregas pendientes y su registro.",
+		Tags:        []string{"webhooks"},
+		Auth:        openapi.Admin,
+		Responses: []openapi.Response{
+			{Status: http.StatusNoContent, Description: "Webhook eliminado"},
+			{Status: http.StatusNotFound, Description: "Webhook no encontrado"},
+		},
+	}
+	opListDeliveries = openapi.Operation{
+		ID:          "listWebhookDeliveries",
+		Summary:     "Registro de entregas",
+		Description: "Devuelve las entregas del webhook, de la más reciente a la más antigua.",
+		Tags:        []string{"webhooks"},
+		Auth:        openapi.Admin,
+		Params: []openapi.Param{
+			{Name: "status", Description: "Filtrar por estado", Enum: []string{models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead}},
+			deliveryLimitParam,
+		},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Entregas", Schema: []models.WebhookDelivery{}},
+			{Status: http.StatusBadRequest, Description: "Filtro inválido"},
+			{Status: http.StatusNotFound, Description: "Webhook no encontrado"},
+		},
+	}
+	opLegacyUpdateUser = openapi.Operation{
+		ID:          "legacyUpdateUser",
+		Summary:     "Actualizar usuario",
+		Description: "Actualiza los campos no vacíos del usuario.",
+		Tags:        []string{"users"},
+		Auth:        openapi.Admin,
+		Successor:   "/api/v1/users/{id}",
+		Params:      []openapi.Param{ifMatchParam},
+		Body:        &openapi.Body{Schema: controllers.LegacyUpdateInput{}, Files: []string{"image"}},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Usuario actualizado", ContentType: []string{"text/plain"}},
+			{Status: http.StatusNotFound, Description: "Usuario no encontrado"},
+			{Status: http.StatusPreconditionFailed, Description: "La versión cambió"},
+			{Status: http.StatusUnprocessableEntity, Description: "Errores por campo"},
+		},
+	}
+	opGraphQL = openapi.Operation{
+		ID:          "graphql",
+		Summary:     "Consulta GraphQL",
+		Description: "Ejecuta una operación GraphQL: las consultas users, user, me y zones y las mutaciones registerUser, updateUser y deleteUser. El token es el mismo de ?token=; cada campo exige los permisos de su ruta REST y sus errores llevan el mismo código en extensions.code. Las consultas que superan GRAPHQL_MAX_DEPTH o GRAPHQL_MAX_COMPLEXITY se rechazan sin ejecutarse.",
+		Tags:        []string{"graphql"},
+		Auth:        openapi.Optional,
+		Body:        &openapi.Body{Schema: gql.Request{}, JSONOnly: true},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Resultado; los errores de cada campo van en errors", Schema: gql.Response{}},
+			{Status: http.StatusBadRequest, Description: "Consulta mal escrita, inválida o que supera los límites", Schema: gql.Response{}, Problem: true},
+		},
+	}
+	opStreamEvents = openapi.Operation{
+		ID:          "streamEvents",
+		Summary:     "Eventos de usuarios (SSE)",
+		Description: "Envía como Server-Sent Events los cambios de usuarios (user.created, user.updated, user.deleted) con el usuario en data. Un admin recibe todos, o los de ?zona=; el resto, los de su zona. Con Last-Event-ID reanuda tras el último evento recibido; si ya no se pueden recuperar llega un evento reset y hay que recargar los usuarios. Solo llegan los cambios hechos por esta instancia.",
+		Tags:        []string{"events"},
+		Auth:        openapi.Authenticated,
+		Streaming:   true,
+		Params:      eventParams,
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Flujo de eventos; cada data es un Event", ContentType: []string{"text/event-stream"}, Schema: events.Event{}},
+			{Status: http.StatusBadRequest, Description: "Last-Event-ID inválido"},
+		},
+	}
+	opEventsSocket = openapi.Operation{
+		ID:          "eventsSocket",
+		Summary:     "Eventos de usuarios (WebSocket)",
+		Description: "Los mismos eventos que GET /events, uno por mensaje de texto con un Event en JSON. Para reanudar se usa ?lastEventId=.",
+		Tags:        []string{"events"},
+		Auth:        openapi.Authenticated,
+		Streaming:   true,
+		Params:      eventParams[1:],
+		Responses: []openapi.Response{
+			{Status: http.StatusSwitchingProtocols, Description: "Conexión WebSocket abierta"},
+			{Status: http.StatusBadRequest, Description: "lastEventId inválido o la petición no es un WebSocket"},
+		},
+	}
+	opLive = openapi.Operation{
+		ID:          "live",
+		Summary:     "Liveness",
+		Description: "Indica que el proceso está vivo; no consulta la BD.",
+		Tags:        []string{"health"},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Vivo", Schema: map[string]string{}},
+		},
+	}
+	opReady = openapi.Operation{
+		ID:          "ready",
+		Summary:     "Readiness",
+		Description: "Indica si la API puede atender peticiones según el último ping a la BD principal; las réplicas caídas solo se informan.",
+		Tags:        []string{"health"},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Lista", Schema: controllers.ReadyResponse{}},
+			{Status: http.StatusServiceUnavailable, Description: "La BD principal no responde", Schema: controllers.ReadyResponse{}},
+		},
+	}
+)
+
+var (
+	ifMatchParam     = openapi.Param{Name: "If-Match", In: "header", Description: "ETag leído; si no coincide con la versión actual responde 412 con el estado actual"}
+	ifNoneMatchParam = openapi.Param{Name: "If-None-Match", In: "header", Description: "ETag en caché; si sigue vigente responde 304"}
+
+	eventParams = []openapi.Param{
+		{Name: "Last-Event-ID", In: "header", Type: "integer", Description: "ID del último evento recibido"},
+		{Name: "lastEventId", Type: "integer", Description: "ID del último evento recibido, para clientes que no pueden enviar la cabecera"},
+		{Name: "zona", Description: "Solo los eventos de esta zona (solo admin)"},
+	}
+
+	userResponses = []openapi.Response{
+		{Status: http.StatusOK, Description: "Usuario", Schema: models.User{}, Headers: []string{"ETag"}},
+		{Status: http.StatusNotModified, Description: "No modificado", Headers: []string{"ETag"}},
+		{Status: http.StatusNotFound, Description: "Usuario no encontrado"},
+	}
+	updateResponses = []openapi.Response{
+		{Status: http.StatusOK, Description: "Usuario actualizado", Schema: models.User{}, Headers: []string{"ETag"}},
+		{Status: http.StatusNotFound, Description: "Usuario no encontrado"},
+		{Status: http.StatusUnprocessableEntity, Description: "Errores por campo"},
+	}
+)
+
+// legacy es op en una ruta anterior obsoleta, sustituida por successor. Las
+// rutas anteriores no exigen If-Match aunque REQUIRE_IF_MATCH esté activo.
+func legacy(op openapi.Operation, successor string) openapi.Operation {
+	op.ID = "legacy" + string(op.ID[0]-'a'+'A') + op.ID[1:]
+	op.Successor = successor
+	if op.IfMatch {
+		op.IfMatch = false
+		op.Params = append(op.Params[:len(op.Params):len(op.Params)], ifMatchParam)
+		op.Responses = append(op.Responses[:len(op.Responses):len(op.Responses)], openapi.Response{Status: http.StatusPreconditionFailed, Description: "La versión cambió"})
+	}
+	return op
+}
@@ -2,42 +2,126 @@ package routes
 
 import (
 	"api3/src/controllers"
-	"api3/src/utils"
+	"api3/src/openapi"
+	"api3/src/problem"
+	"encoding/json"
+	"log"
 	"net/http"
+	"os"
+	"strings"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/gorilla/mux"
 )
 
-// Middleware CORS
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Cabeceras CORS
-		w.Header().Set("Access-Control-Allow-Origin", "*") // Cambia "*" por tu dominio si quieres
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		// Preflight (OPTIONS)
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
+// SetupRoutes registra las rutas y sirve su especificación en /openapi.json.
+// Con OPENAPI_VALIDATE (requests, responses o all; pensado para desarrollo y
+// pruebas) valida cada petición y respuesta contra ella.
+func SetupRoutes(users *controllers.UserHandler, zones *controllers.ZoneHandler, hooks *controllers.WebhookHandler, graph *controllers.GraphQLHandler, health *controllers.HealthHandler) *mux.Router {
+	r, doc := setup(users, zones, hooks, graph, health)
+	r.HandleFunc("/openapi.json", serveSpec(doc)).Methods("GET")
+
+	switch mode := strings.ToLower(os.Getenv("OPENAPI_VALIDATE")); mode {
+	case "", "0", "false", "off":
+	default:
+		if mode == "1" || mode == "true" {
+			mode = openapi.ValidateAll
+		}
+		validate, err := openapi.Middleware(doc, mode)
+		if err != nil {
+			log.Fatal("❌ OPENAPI_VALIDATE: ", err)
 		}
+		r.Use(validate)
+		log.Printf("⚠️  Validando contra la especificación OpenAPI (%s)", mode)
+	}
+	return r
+}
 
-		next.ServeHTTP(w, r)
-	})
+// OpenAPI devuelve la especificación de las rutas sin necesitar la BD
+func OpenAPI() *openapi3.T {
+	_, doc := setup(&controllers.UserHandler{}, &controllers.ZoneHandler{}, &controllers.WebhookHandler{}, &controllers.GraphQLHandler{}, &controllers.HealthHandler{})
+	return doc
 }
 
-func SetupRoutes() *mux.Router {
+func setup(users *controllers.UserHandler, zones *controllers.ZoneHandler, hooks *controllers.WebhookHandler, graph *controllers.GraphQLHandler, health *controllers.HealthHandler) (*mux.Router, *openapi3.T) {
+	// CORS lo aplica utils.CORS alrededor del router (main.go), así cubre
+	// también los preflight de rutas sin método OPTIONS
 	r := mux.NewRouter()
 
-	// Aplica el middleware CORS globalmente
-	r.Use(corsMiddleware)
+	spec := openapi.New("API Zoo", "1.0", "Usuarios, autenticación y zonas del zoológico. Los errores son application/problem+json (RFC 7807) con un código estable en code.")
+	root := api{router: r, spec: spec}
 
-	// Define rutas después de aplicar el middleware
-	r.HandleFunc("/login", controllers.Login).Methods("POST")
-	r.HandleFunc("/register", controllers.Register).Methods("POST")
-	r.HandleFunc("/update/{id}", utils.RequireRole("admin")(controllers.UpdateUser)).Methods("PUT")
-	r.HandleFunc("/delete/{id}", utils.RequireRole("admin")(controllers.DeleteUser)).Methods("DELETE")
-	r.HandleFunc("/users", utils.RequireRole("admin")(controllers.GetAllUsers)).Methods("GET")
+	// API v1 con rutas orientadas a recursos
+	v1 := api{router: r.PathPrefix("/api/v1").Subrouter(), prefix: "/api/v1", spec: spec}
+	v1.handle("POST", "/auth/login", users.Login, opLogin)
+	v1.handle("POST", "/auth/invitations/accept", users.AcceptInvitation, opAcceptInvitation)
+	v1.handle("GET", "/users", users.GetAllUsers, opListUsers)
+	v1.handle("POST", "/users", users.Register, opRegisterUser)
+	v1.handle("POST", "/users/import", users.ImportUsers, opImportUsers)
+	v1.handle("GET", "/users/export", users.ExportUsers, opExportUsers)
+	v1.handle("GET", "/users/search", users.SearchUsers, opSearchUsers)
+	v1.handle("GET", "/users/by-username/{username}", users.GetUserByUsername, opGetUserByUsername)
+	v1.handle("GET", "/users/{id:[0-9]+}", users.GetUser, opGetUser)
+	v1.handle("PUT", "/users/{id:[0-9]+}", users.ReplaceUser, opReplaceUser)
+	v1.handle("PATCH", "/users/{id:[0-9]+}", users.PatchUser, opPatchUser)
+	v1.handle("DELETE", "/users/{id:[0-9]+}", users.DeleteUser, opDeleteUser)
+	v1.handle("GET", "/users/{id:[0-9]+}/image", users.GetUserImage, opGetUserImage)
+	v1.handle("GET", "/zones", zones.ListZones, opListZones)
+	v1.handle("POST", "/zones", zones.CreateZone, opCreateZone)
+	v1.handle("GET", "/webhooks", hooks.ListWebhooks, opListWebhooks)
+	v1.handle("POST", "/webhooks", hooks.CreateWebhook, opCreateWebhook)
+	v1.handle("GET", "/webhooks/dead-letters", hooks.ListDeadLetters, opListDeadLetters)
+	v1.handle("GET", "/webhooks/deliveries/{id:[0-9]+}", hooks.GetDelivery, opGetDelivery)
+	v1.handle("POST", "/webhooks/deliveries/{id:[0-9]+}/redeliver", hooks.Redeliver, opRedeliver)
+	v1.handle("GET", "/webhooks/{id:[0-9]+}", hooks.GetWebhook, opGetWebhook)
+	v1.handle("PATCH", "/webhooks/{id:[0-9]+}", hooks.UpdateWebhook, opUpdateWebhook)
+	v1.handle("DELETE", "/webhooks/{id:[0-9]+}", hooks.DeleteWebhook, opDeleteWebhook)
+	v1.handle("GET", "/webhooks/{id:[0-9]+}/deliveries", hooks.ListDeliveries, opListDeliveries)
 
-	return r
+	// Rutas anteriores, obsoletas: se mantienen mientras los clientes migran
+	root.handle("POST", "/login", users.Login, legacy(opLogin, "/api/v1/auth/login"))
+	root.handle("POST", "/register", users.Register, legacy(opRegisterUser, "/api/v1/users"))
+	root.handle("PUT", "/update/{id}", users.UpdateUser, opLegacyUpdateUser)
+	root.handle("DELETE", "/delete/{id}", users.DeleteUser, legacy(opDeleteUser, "/api/v1/users/{id}"))
+	root.handle("GET", "/users", users.GetAllUsers, legacy(opListUsers, "/api/v1/users"))
+	root.handle("GET", "/users/by-username/{username}", users.GetUserByUsername, legacy(opGetUserByUsername, "/api/v1/users/by-username/{username}"))
+	root.handle("GET", "/users/{id:[0-9]+}", users.GetUser, legacy(opGetUser, "/api/v1/users/{id}"))
+	root.handle("GET", "/users/{id:[0-9]+}/image", users.GetUserImage, legacy(opGetUserImage, "/api/v1/users/{id}/image"))
+
+	// Las rutas inexistentes también responden con problem+json
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		problem.Write(w, r, problem.New(problem.CodeNotFound))
+	})
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		problem.Write(w, r, problem.New(problem.CodeMethodNotAllowed))
+	})
+
+	// Usuarios con su zona y su rol en una sola petición
+	root.handle("POST", "/graphql", graph.Serve, opGraphQL)
+
+	// Cambios de usuarios en tiempo real para el panel
+	root.handle("GET", "/events", users.StreamEvents, opStreamEvents)
+	root.handle("GET", "/events/ws", users.EventsSocket, opEventsSocket)
+
+	// Sondas para el orquestador
+	root.handle("GET", "/healthz", health.Live, opLive)
+	root.handle("GET", "/readyz", health.Ready, opReady)
+
+	doc, err := spec.Document()
+	if err != nil {
+		panic(err)
+	}
+	return r, doc
+}
+
+// Sirve la especificación ya serializada
+func serveSpec(doc *openapi3.T) http.HandlerFunc {
+	body, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}
 }
@@ -0,0 +1,58 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// docs/openapi.json debe ser lo que generan las rutas actuales
+func TestOpenAPIFileUpToDate(t *testing.T) {
+	var want bytes.Buffer
+	enc := json.NewEncoder(&want)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(OpenAPI()); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("../../docs/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Error("docs/openapi.json está desactualizado: regenéralo con go run . openapi > docs/openapi.json")
+	}
+}
+
+// Quita el patrón de las variables: {id:[0-9]+} → {id}
+var varPattern = regexp.MustCompile(`:[^}]+\}`)
+
+// Toda ruta registrada debe estar en la especificación con su método
+func TestEveryRouteDocumented(t *testing.T) {
+	r, doc := setup(nil, nil, nil, nil, nil)
+	err := r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		tpl, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil // prefijos como /api/v1
+		}
+		path := varPattern.ReplaceAllString(tpl, "}")
+		item := doc.Paths.Find(path)
+		for _, method := range methods {
+			if item == nil || item.GetOperation(strings.ToUpper(method)) == nil {
+				t.Errorf("%s %s no está en la especificación", method, tpl)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
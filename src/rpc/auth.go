@@ -0,0 +1,66 @@
+package rpc
+
+import (
+	"api3/src/i18n"
+	"api3/src/models"
+	"api3/src/problem"
+	"api3/src/utils"
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Auth es lo que exige un método para atender la llamada
+type Auth int
+
+const (
+	// Authenticated es el valor de los métodos que no están en la política
+	Authenticated Auth = iota
+	Public
+	Admin
+)
+
+// authInterceptor valida el JWT de la metadata authorization ("Bearer
+// <token>"), guarda sus datos y el idioma (accept-language) en el contexto
+// y aplica policy. Un token inválido se rechaza aunque el método sea
+// público, como en REST.
+func authInterceptor(policy map[string]Auth) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		ctx = i18n.WithLocale(ctx, i18n.Negotiate(first(md, "accept-language")))
+
+		required := policy[info.FullMethod]
+		token := bearerToken(first(md, "authorization"))
+		if token == "" {
+			if required != Public {
+				return nil, problem.New(problem.CodeTokenMissing)
+			}
+			return handler(ctx, req)
+		}
+		claims, err := utils.ValidateToken(token)
+		if err != nil {
+			return nil, problem.New(problem.CodeTokenInvalid)
+		}
+		if required == Admin && !strings.EqualFold(claims.Role, models.RoleAdmin) {
+			return nil, problem.New(problem.CodeForbidden)
+		}
+		return handler(utils.WithClaims(ctx, claims), req)
+	}
+}
+
+func bearerToken(header string) string {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
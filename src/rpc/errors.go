@@ -0,0 +1,112 @@
+package rpc
+
+import (
+	"api3/src/i18n"
+	"api3/src/problem"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// ErrorDomain identifica los códigos de ErrorInfo.Reason
+const ErrorDomain = "api-zoo"
+
+// Status convierte el error de un método en un estado gRPC. Un
+// *problem.Problem se traduce al idioma de la llamada y lleva su código
+// estable en ErrorInfo y los errores de campo en BadRequest; cualquier otro
+// error se responde como Internal.
+func Status(ctx context.Context, err error) *status.Status {
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+	var prob *problem.Problem
+	if !errors.As(err, &prob) {
+		prob = problem.Internal(err)
+	}
+	prob.Translate(i18n.FromContext(ctx))
+
+	msg := prob.Title
+	if prob.Detail != "" {
+		msg += ": " + prob.Detail
+	}
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: prob.Code, Domain: ErrorDomain}}
+	if len(prob.Errors) > 0 {
+		bad := &errdetails.BadRequest{}
+		for _, fe := range prob.Errors {
+			bad.FieldViolations = append(bad.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       snakeCase(fe.Field),
+				Description: fe.Detail,
+				Reason:      fe.Code,
+			})
+		}
+		details = append(details, bad)
+	}
+	st := status.New(codeOf(prob), msg)
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		st = withDetails
+	}
+	return st
+}
+
+// Código gRPC equivalente al estado HTTP del problema
+func codeOf(prob *problem.Problem) codes.Code {
+	switch prob.Status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound, http.StatusGone:
+		return codes.NotFound
+	case http.StatusConflict:
+		if prob.Code == problem.CodeUserExists || prob.Code == problem.CodeZoneExists {
+			return codes.AlreadyExists
+		}
+		return codes.Aborted // otro cambio se adelantó: reintentar
+	case http.StatusPreconditionFailed, http.StatusPreconditionRequired:
+		return codes.FailedPrecondition
+	default:
+		return codes.Internal
+	}
+}
+
+// Los campos de los errores usan los nombres JSON; en gRPC van como en el
+// .proto (displayName → display_name)
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// errorInterceptor convierte en estados gRPC los errores de los métodos
+func errorInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		var prob *problem.Problem
+		if errors.As(err, &prob) && errors.Unwrap(prob) != nil {
+			log.Printf("❌ %s: %v", info.FullMethod, errors.Unwrap(prob))
+		} else if _, isStatus := status.FromError(err); !isStatus && prob == nil {
+			log.Printf("❌ %s: %v", info.FullMethod, err)
+		}
+		return nil, Status(ctx, err).Err()
+	}
+	return resp, nil
+}
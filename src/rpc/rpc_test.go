@@ -0,0 +1,138 @@
+package rpc
+
+import (
+	"api3/src/problem"
+	"api3/src/rpc/userpb"
+	"api3/src/utils"
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeUsers responde con lo justo para probar los interceptores
+type fakeUsers struct {
+	userpb.UnimplementedUserServiceServer
+}
+
+func (fakeUsers) GetUser(ctx context.Context, req *userpb.GetUserRequest) (*userpb.User, error) {
+	claims, _ := utils.ClaimsFrom(ctx)
+	return &userpb.User{Id: req.GetId(), Zona: claims.Zona}, nil
+}
+
+func (fakeUsers) CreateUser(ctx context.Context, req *userpb.CreateUserRequest) (*userpb.User, error) {
+	return nil, problem.New(problem.CodeValidation).WithFields(map[string]string{"displayName": problem.FieldTooLong})
+}
+
+func (fakeUsers) ValidateToken(ctx context.Context, req *userpb.ValidateTokenRequest) (*userpb.ValidateTokenResponse, error) {
+	return &userpb.ValidateTokenResponse{Valid: true}, nil
+}
+
+func newClient(t *testing.T) userpb.UserServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	s := NewServer(fakeUsers{})
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return userpb.NewUserServiceClient(conn)
+}
+
+func withToken(t *testing.T, role string) context.Context {
+	t.Helper()
+	token, err := utils.GenerateToken(1, role, "norte")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	client := newClient(t)
+	tests := []struct {
+		name string
+		ctx  context.Context
+		call func(ctx context.Context) error
+		code codes.Code
+	}{
+		{"sin token", context.Background(), func(ctx context.Context) error {
+			_, err := client.GetUser(ctx, &userpb.GetUserRequest{Lookup: &userpb.GetUserRequest_Id{Id: 1}})
+			return err
+		}, codes.Unauthenticated},
+		{"rol insuficiente", withToken(t, "user"), func(ctx context.Context) error {
+			_, err := client.GetUser(ctx, &userpb.GetUserRequest{Lookup: &userpb.GetUserRequest_Id{Id: 1}})
+			return err
+		}, codes.PermissionDenied},
+		{"admin", withToken(t, "admin"), func(ctx context.Context) error {
+			user, err := client.GetUser(ctx, &userpb.GetUserRequest{Lookup: &userpb.GetUserRequest_Id{Id: 1}})
+			if err == nil && user.Zona != "norte" {
+				t.Errorf("admin: zona %q, los datos del token no llegaron al método", user.Zona)
+			}
+			return err
+		}, codes.OK},
+		{"método público", context.Background(), func(ctx context.Context) error {
+			_, err := client.ValidateToken(ctx, &userpb.ValidateTokenRequest{})
+			return err
+		}, codes.OK},
+		{"token inválido en método público", metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer x"), func(ctx context.Context) error {
+			_, err := client.ValidateToken(ctx, &userpb.ValidateTokenRequest{})
+			return err
+		}, codes.Unauthenticated},
+		{"método sin política", withToken(t, "user"), func(ctx context.Context) error {
+			_, err := client.ListUsers(ctx, &userpb.ListUsersRequest{})
+			return err
+		}, codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		if code := status.Code(tt.call(tt.ctx)); code != tt.code {
+			t.Errorf("%s: código %v, se esperaba %v", tt.name, code, tt.code)
+		}
+	}
+}
+
+func TestProblemStatus(t *testing.T) {
+	client := newClient(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "accept-language", "en")
+	_, err := client.CreateUser(ctx, &userpb.CreateUserRequest{})
+
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("código %v, se esperaba InvalidArgument", st.Code())
+	}
+	var reason, field string
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			reason = d.Reason
+		case *errdetails.BadRequest:
+			field = d.FieldViolations[0].Field
+		}
+	}
+	if reason != problem.CodeValidation || field != "display_name" {
+		t.Errorf("detalles %q / %q, se esperaba %q / display_name", reason, field, problem.CodeValidation)
+	}
+}
+
+func TestReflectionOptIn(t *testing.T) {
+	for _, enabled := range []string{"", "true"} {
+		t.Setenv("GRPC_REFLECTION", enabled)
+		_, registered := NewServer(fakeUsers{}).GetServiceInfo()["grpc.reflection.v1.ServerReflection"]
+		if registered != (enabled == "true") {
+			t.Errorf("GRPC_REFLECTION=%q: reflexión registrada = %v", enabled, registered)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Package rpc sirve la API de usuarios por gRPC para los demás servicios
+// del zoológico
+package rpc
+
+import (
+	"api3/src/rpc/userpb"
+	"net"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// Permisos de cada método, los mismos que sus rutas REST
+var userPolicy = map[string]Auth{
+	userpb.UserService_GetUser_FullMethodName:       Admin,
+	userpb.UserService_ListUsers_FullMethodName:     Admin,
+	userpb.UserService_CreateUser_FullMethodName:    Public, // role=admin exige un admin (lo comprueba el servicio)
+	userpb.UserService_UpdateUser_FullMethodName:    Admin,
+	userpb.UserService_DeleteUser_FullMethodName:    Admin,
+	userpb.UserService_ValidateToken_FullMethodName: Public,
+}
+
+// NewServer prepara el servidor con el servicio de usuarios y la
+// autenticación por metadata. La reflexión (para grpcurl y similares) solo
+// se activa con GRPC_REFLECTION=true: describe todo el servicio a
+// cualquiera que llegue al puerto.
+func NewServer(users userpb.UserServiceServer) *grpc.Server {
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(errorInterceptor, authInterceptor(userPolicy)))
+	userpb.RegisterUserServiceServer(s, users)
+	if reflectionEnabled() {
+		reflection.Register(s)
+	}
+	return s
+}
+
+func reflectionEnabled() bool {
+	switch strings.ToLower(os.Getenv("GRPC_REFLECTION")) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
+
+// ListenAndServe atiende s en addr (p. ej. ":9090")
+func ListenAndServe(addr string, s *grpc.Server) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(lis)
+}
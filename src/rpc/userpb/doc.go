@@ -0,0 +1,4 @@
+// Package userpb es el código generado de users.proto; no se edita a mano
+package userpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative users.proto
@@ -0,0 +1,120 @@
+package search
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Index es un índice invertido en memoria, para bases de datos sin texto
+// completo. Se reconstruye con load cuando pasa ttl o tras Invalidate.
+type Index struct {
+	fields []Field
+	ttl    time.Duration
+	load   func(ctx context.Context) ([]Doc, error)
+
+	mu       sync.Mutex
+	docs     []Doc
+	words    []string         // palabras distintas, ordenadas
+	postings map[string][]int // palabra → posiciones en docs
+	builtAt  time.Time
+}
+
+func NewIndex(fields []Field, ttl time.Duration, load func(ctx context.Context) ([]Doc, error)) *Index {
+	return &Index{fields: fields, ttl: ttl, load: load}
+}
+
+// Invalidate hace que la próxima búsqueda recargue los documentos
+func (ix *Index) Invalidate() {
+	ix.mu.Lock()
+	ix.builtAt = time.Time{}
+	ix.mu.Unlock()
+}
+
+// Search devuelve los limit documentos más relevantes para query
+func (ix *Index) Search(ctx context.Context, query string, limit int) ([]Hit, error) {
+	ix.mu.Lock()
+	if ix.builtAt.IsZero() || time.Since(ix.builtAt) > ix.ttl {
+		if err := ix.rebuild(ctx); err != nil {
+			ix.mu.Unlock()
+			return nil, err
+		}
+	}
+	// Una reconstrucción crea estructuras nuevas: estas no cambian
+	docs, words, postings := ix.docs, ix.words, ix.postings
+	ix.mu.Unlock()
+
+	var candidates map[int]bool
+	for _, term := range uniqueTerms(query) {
+		matched := map[int]bool{}
+		for _, word := range matchingWords(words, term) {
+			for _, pos := range postings[word] {
+				if candidates == nil || candidates[pos] {
+					matched[pos] = true
+				}
+			}
+		}
+		candidates = matched
+		if len(candidates) == 0 {
+			return nil, nil
+		}
+	}
+
+	selected := make([]Doc, 0, len(candidates))
+	for pos := range candidates {
+		selected = append(selected, docs[pos])
+	}
+	return Rank(ix.fields, selected, query, limit), nil
+}
+
+func (ix *Index) rebuild(ctx context.Context) error {
+	docs, err := ix.load(ctx)
+	if err != nil {
+		return err
+	}
+	postings := map[string][]int{}
+	for pos, doc := range docs {
+		seen := map[string]bool{}
+		for _, value := range doc.Values {
+			for _, tok := range tokenize(value) {
+				if !seen[tok.text] {
+					seen[tok.text] = true
+					postings[tok.text] = append(postings[tok.text], pos)
+				}
+			}
+		}
+	}
+	words := make([]string, 0, len(postings))
+	for word := range postings {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	ix.docs, ix.words, ix.postings, ix.builtAt = docs, words, postings, time.Now()
+	return nil
+}
+
+// Palabras que empiezan por term (búsqueda binaria) más las que se le
+// parecen; la lista aproximada se recorre entera, basta para miles de
+// palabras
+func matchingWords(words []string, term string) []string {
+	var matched []string
+	i := sort.SearchStrings(words, term)
+	for ; i < len(words) && strings.HasPrefix(words[i], term); i++ {
+		matched = append(matched, words[i])
+	}
+	if maxDistance(term) == 0 {
+		return matched
+	}
+	for _, word := range words {
+		if strings.HasPrefix(word, term) {
+			continue
+		}
+		if score, _ := matchToken(term, word); score > 0 {
+			matched = append(matched, word)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,174 @@
+package search
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Field es un campo buscable; Weight multiplica la relevancia de sus
+// coincidencias (p. ej. el username pesa más que la zona)
+type Field struct {
+	Name   string
+	Weight float64
+}
+
+// Doc es un elemento buscable: Values va en el mismo orden que los campos.
+// Payload es lo que se devuelve con el resultado (p. ej. el usuario).
+type Doc struct {
+	ID      int
+	Values  []string
+	Payload interface{}
+}
+
+// Hit es un resultado con su relevancia y el texto de cada campo que
+// coincidió, con las coincidencias entre <mark> y </mark> (escapado como
+// HTML)
+type Hit struct {
+	Doc        Doc
+	Score      float64
+	Highlights map[string]string
+}
+
+// Relevancia de una palabra del documento para un término: exacta, por
+// prefijo (mejor cuanto más completa) o aproximada (peor cuantos más
+// errores). marked es cuántas runas resaltar (-1 = la palabra entera).
+func matchToken(term, word string) (score float64, marked int) {
+	if word == term {
+		return 1, -1
+	}
+	termLen, wordLen := utf8.RuneCountInString(term), utf8.RuneCountInString(word)
+	if strings.HasPrefix(word, term) {
+		return 0.5 + 0.4*float64(termLen)/float64(wordLen), termLen
+	}
+
+	limit := maxDistance(term)
+	if limit == 0 {
+		return 0, 0
+	}
+	if d := distance(term, word, limit); d <= limit {
+		return 0.4 - 0.1*float64(d), -1
+	}
+	// Prefijo con errores: "kepe" encuentra "keeper1"
+	if wordLen > termLen {
+		prefix := string([]rune(word)[:termLen])
+		if d := distance(term, prefix, limit); d <= limit {
+			return 0.3 - 0.1*float64(d), termLen
+		}
+	}
+	return 0, 0
+}
+
+// Rank puntúa los documentos para query y devuelve los limit mejores. Un
+// documento debe coincidir con todos los términos.
+func Rank(fields []Field, docs []Doc, query string, limit int) []Hit {
+	terms := uniqueTerms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var hits []Hit
+	for _, doc := range docs {
+		if hit, ok := rankDoc(fields, doc, terms); ok {
+			hits = append(hits, hit)
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Doc.ID < hits[j].Doc.ID
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+type span struct{ start, end int }
+
+func rankDoc(fields []Field, doc Doc, terms []string) (Hit, bool) {
+	tokens := make([][]token, len(fields))
+	for i := range fields {
+		if i < len(doc.Values) {
+			tokens[i] = tokenize(doc.Values[i])
+		}
+	}
+
+	hit := Hit{Doc: doc}
+	marks := make([][]span, len(fields))
+	for _, term := range terms {
+		best := 0.0
+		for i, field := range fields {
+			for _, tok := range tokens[i] {
+				score, marked := matchToken(term, tok.text)
+				if score == 0 {
+					continue
+				}
+				best = max(best, score*field.Weight)
+				end := tok.end
+				if marked >= 0 {
+					end = tok.start + runePrefixLen(doc.Values[i][tok.start:tok.end], marked)
+				}
+				marks[i] = append(marks[i], span{tok.start, end})
+			}
+		}
+		if best == 0 {
+			return hit, false
+		}
+		hit.Score += best
+	}
+
+	hit.Highlights = map[string]string{}
+	for i, field := range fields {
+		if len(marks[i]) > 0 {
+			hit.Highlights[field.Name] = highlight(doc.Values[i], marks[i])
+		}
+	}
+	return hit, true
+}
+
+// Bytes que ocupan las primeras n runas de s
+func runePrefixLen(s string, n int) int {
+	for i := range s {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return len(s)
+}
+
+// Marca los tramos de s (uniendo los que se solapan) y escapa el resto
+func highlight(s string, spans []span) string {
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	var b strings.Builder
+	pos := 0
+	for i := 0; i < len(spans); i++ {
+		cur := spans[i]
+		for i+1 < len(spans) && spans[i+1].start <= cur.end {
+			i++
+			cur.end = max(cur.end, spans[i].end)
+		}
+		b.WriteString(html.EscapeString(s[pos:cur.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(s[cur.start:cur.end]))
+		b.WriteString("</mark>")
+		pos = cur.end
+	}
+	b.WriteString(html.EscapeString(s[pos:]))
+	return b.String()
+}
+
+func uniqueTerms(query string) []string {
+	seen := map[string]bool{}
+	var terms []string
+	for _, term := range Tokenize(query) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
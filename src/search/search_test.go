@@ -0,0 +1,93 @@
+package search
+
+import (
+	"context"
+	"testing"
+)
+
+var testFields = []Field{{Name: "username", Weight: 3}, {Name: "displayName", Weight: 2}, {Name: "zona", Weight: 1}}
+
+var testDocs = []Doc{
+	{ID: 1, Values: []string{"keeper1", "Ana López", "norte"}},
+	{ID: 2, Values: []string{"ana.ruiz", "Ana Ruiz", "sur"}},
+	{ID: 3, Values: []string{"zoo_admin", "", "anaconda"}},
+}
+
+func ids(hits []Hit) []int {
+	var out []int
+	for _, h := range hits {
+		out = append(out, h.Doc.ID)
+	}
+	return out
+}
+
+func TestRank(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		// El username pesa más que el nombre y este más que la zona
+		{"ana", []int{2, 1, 3}},
+		{"ana sur", []int{2}},
+		// Sin tildes y con un error de tipeo
+		{"lopes", []int{1}},
+		{"kepe", []int{1}},
+		{"xyz", nil},
+	}
+	for _, tt := range tests {
+		got := ids(Rank(testFields, testDocs, tt.query, 10))
+		if len(got) != len(tt.want) {
+			t.Errorf("%q: %v, quería %v", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%q: %v, quería %v", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	hits := Rank(testFields, []Doc{{ID: 1, Values: []string{"a<b>", "Ángel Ángeles", ""}}}, "ange", 1)
+	if len(hits) != 1 {
+		t.Fatalf("hits = %d", len(hits))
+	}
+	if got, want := hits[0].Highlights["displayName"], "<mark>Ánge</mark>l <mark>Ánge</mark>les"; got != want {
+		t.Errorf("displayName = %q, quería %q", got, want)
+	}
+	if _, ok := hits[0].Highlights["username"]; ok {
+		t.Error("username no coincide y no debe resaltarse")
+	}
+
+	hits = Rank(testFields, []Doc{{ID: 1, Values: []string{"x<y", "", ""}}}, "y", 1)
+	if got, want := hits[0].Highlights["username"], "x&lt;<mark>y</mark>"; got != want {
+		t.Errorf("username = %q, quería %q", got, want)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	loads := 0
+	ix := NewIndex(testFields, 1<<62, func(ctx context.Context) ([]Doc, error) {
+		loads++
+		return testDocs, nil
+	})
+	for _, query := range []string{"ana", "lopes", "ana sur"} {
+		hits, err := ix.Search(context.Background(), query, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := ids(Rank(testFields, testDocs, query, 10)); len(ids(hits)) != len(want) {
+			t.Errorf("%q: índice %v, Rank %v", query, ids(hits), want)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("cargas = %d, quería 1", loads)
+	}
+	ix.Invalidate()
+	ix.Search(context.Background(), "ana", 1)
+	if loads != 2 {
+		t.Errorf("tras Invalidate cargas = %d, quería 2", loads)
+	}
+}
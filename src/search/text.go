@@ -0,0 +1,121 @@
+// Package search implementa la búsqueda de texto de usuarios: coincidencia
+// por prefijo y aproximada, ranking y resaltado. El ranking se aplica igual a
+// los candidatos del índice de texto completo de la BD y a los del índice en
+// memoria (Index).
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// token es una palabra normalizada y su posición (en bytes) en el texto
+// original, para poder resaltarla
+type token struct {
+	text       string
+	start, end int
+}
+
+// Tokenize divide s en palabras normalizadas (minúsculas y sin tildes)
+func Tokenize(s string) []string {
+	tokens := tokenize(s)
+	terms := make([]string, len(tokens))
+	for i, t := range tokens {
+		terms[i] = t.text
+	}
+	return terms
+}
+
+// Las palabras son secuencias de letras y dígitos; "ana.lopez" son dos
+func tokenize(s string) []token {
+	var tokens []token
+	var b strings.Builder
+	start := -1
+	for i, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			b.WriteRune(fold(r))
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, token{text: b.String(), start: start, end: i})
+			b.Reset()
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{text: b.String(), start: start, end: len(s)})
+	}
+	return tokens
+}
+
+// Pasa a minúscula y quita la tilde. Cada runa da exactamente una runa, así
+// las posiciones en runas del texto normalizado sirven en el original.
+func fold(r rune) rune {
+	r = unicode.ToLower(r)
+	if r < utf8.RuneSelf {
+		return r
+	}
+	base, _ := utf8.DecodeRuneInString(norm.NFD.String(string(r)))
+	return base
+}
+
+// Distancia de edición entre a y b contando una transposición de letras
+// vecinas como un solo error (Damerau-Levenshtein restringida), o limit+1
+// si la supera
+func distance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > limit {
+		return limit + 1
+	}
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+// Errores tolerados según el largo del término: ninguno en términos cortos
+// para no devolver medio padrón al escribir dos letras
+func maxDistance(term string) int {
+	switch n := utf8.RuneCountInString(term); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	}
+	return 2
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrBlobNotFound se devuelve cuando la clave no existe en el almacén
+var ErrBlobNotFound = errors.New("blob no encontrado")
+
+// BlobStore guarda archivos binarios (imágenes de perfil) fuera de la BD
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewFromEnv crea el almacén configurado en BLOB_STORE (local o s3)
+func NewFromEnv() (BlobStore, error) {
+	switch strings.ToLower(os.Getenv("BLOB_STORE")) {
+	case "", "local":
+		dir := os.Getenv("BLOB_LOCAL_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		return NewLocalStore(dir)
+	case "s3":
+		return NewS3Store(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Region:    os.Getenv("S3_REGION"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		})
+	default:
+		return nil, fmt.Errorf("BLOB_STORE no soportado: %q (usa local o s3)", os.Getenv("BLOB_STORE"))
+	}
+}
+
+// NewKey genera una clave aleatoria bajo el prefijo indicado
+func NewKey(prefix string) string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return prefix + "/" + hex.EncodeToString(buf)
+}
+
+// ReadAll lee un blob completo en memoria
+func ReadAll(ctx context.Context, store BlobStore, key string) ([]byte, error) {
+	rc, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// Rechaza claves que podrían salir del directorio o del bucket
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("clave de blob inválida: %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("clave de blob inválida: %q", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Servidor mínimo que imita a MinIO: PUT/GET/DELETE sobre /bucket/clave
+// y comprueba que las peticiones vengan firmadas
+func newFakeS3(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=minio/") {
+			http.Error(w, "AccessDenied", http.StatusForbidden)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		sum := sha256.Sum256(body)
+		if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+			http.Error(w, "XAmzContentSHA256Mismatch", http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			objects[r.URL.Path] = body
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				http.Error(w, "NoSuchKey", http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+}
+
+func testRoundTrip(t *testing.T, store BlobStore) {
+	ctx := context.Background()
+	key := NewKey("users")
+
+	if err := store.Put(ctx, key, strings.NewReader("contenido"), "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	data, err := ReadAll(ctx, store, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(data) != "contenido" {
+		t.Fatalf("contenido = %q", data)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("Get tras Delete: err = %v, se esperaba ErrBlobNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete de una clave inexistente: %v", err)
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testRoundTrip(t, store)
+
+	if err := store.Put(context.Background(), "../fuera", strings.NewReader("x"), ""); err == nil {
+		t.Fatal("se esperaba error con una clave fuera del directorio")
+	}
+}
+
+// Varias subidas del mismo contenido a la vez (imágenes deduplicadas)
+func TestLocalStoreConcurrentPut(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Repeat("imagen", 100000)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- store.Put(context.Background(), "images/abc", strings.NewReader(content), "image/png")
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Put concurrente: %v", err)
+		}
+	}
+
+	data, err := ReadAll(context.Background(), store, "images/abc")
+	if err != nil || string(data) != content {
+		t.Fatalf("contenido de %d bytes (%v), se esperaban %d", len(data), err, len(content))
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "images"))
+	if len(entries) != 1 {
+		t.Errorf("quedan temporales: %d archivos en images/", len(entries))
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	server := newFakeS3(t)
+	defer server.Close()
+
+	store, err := NewS3Store(S3Config{
+		Endpoint:  server.URL,
+		Bucket:    "avatars",
+		AccessKey: "minio",
+		SecretKey: "minio123",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testRoundTrip(t, store)
+}
@@ -1,33 +1,78 @@
 package utils
 
 import (
+	"api3/src/problem"
+	"context"
 	"net/http"
 	"strings"
 )
 
+type claimsKey struct{}
+
+// WithClaims guarda en ctx los datos del token ya validado
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFrom devuelve los datos del token de la petición, si lo había
+func ClaimsFrom(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
+// RequireAuth exige un token válido en la URL, sin importar el rol
+func RequireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if claims, ok := tokenClaims(w, r); ok {
+			next(w, r.WithContext(WithClaims(r.Context(), claims)))
+		}
+	}
+}
+
+// OptionalAuth acepta peticiones sin token; si lo hay debe ser válido y sus
+// datos quedan en el contexto para que el controlador decida
+func OptionalAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("token") == "" {
+			next(w, r)
+			return
+		}
+		RequireAuth(next)(w, r)
+	}
+}
+
 func RequireRole(allowedRoles ...string) func(http.HandlerFunc) http.HandlerFunc {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
-			tokenStr := r.URL.Query().Get("token")
-			if tokenStr == "" {
-				http.Error(w, "Token requerido en la URL", http.StatusUnauthorized)
-				return
-			}
-
-			claims, err := ValidateToken(tokenStr)
-			if err != nil {
-				http.Error(w, "Token inválido", http.StatusUnauthorized)
+			claims, ok := tokenClaims(w, r)
+			if !ok {
 				return
 			}
 
 			for _, role := range allowedRoles {
 				if strings.EqualFold(claims.Role, role) {
-					next(w, r)
+					next(w, r.WithContext(WithClaims(r.Context(), claims)))
 					return
 				}
 			}
 
-			http.Error(w, "Acceso no autorizado: rol insuficiente", http.StatusForbidden)
+			problem.Write(w, r, problem.New(problem.CodeForbidden))
 		}
 	}
 }
+
+// Valida el token de la URL; si falla ya respondió con 401
+func tokenClaims(w http.ResponseWriter, r *http.Request) (*Claims, bool) {
+	tokenStr := r.URL.Query().Get("token")
+	if tokenStr == "" {
+		problem.Write(w, r, problem.New(problem.CodeTokenMissing))
+		return nil, false
+	}
+
+	claims, err := ValidateToken(tokenStr)
+	if err != nil {
+		problem.Write(w, r, problem.New(problem.CodeTokenInvalid))
+		return nil, false
+	}
+	return claims, true
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// EnvInt lee un entero de la variable de entorno key o devuelve def
+func EnvInt(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return v
+	}
+	return def
+}
+
+// EnvDuration acepta duraciones de Go ("30s", "5m") o segundos sin unidad
+func EnvDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if d, err := time.ParseDuration(v); err == nil {
+		return d
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	return def
+}
@@ -5,14 +5,18 @@ import (
 	"os"
 )
 
+// SaveFile copia file en path; el error de Close cuenta (puede ser el de
+// una escritura diferida)
 func SaveFile(file io.Reader, path string) (string, error) {
 	out, err := os.Create(path)
 	if err != nil {
 		return "", err
 	}
-	defer out.Close()
 
 	_, err = io.Copy(out, file)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
 		return "", err
 	}
@@ -40,10 +40,49 @@ func ValidateToken(tokenStr string) (*Claims, error) {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	// Una invitación no sirve como token de sesión
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && claims.Subject != invitationSubject {
 		return claims, nil
 	}
 
 	return nil, errors.New("token inválido")
 }
 
+const invitationSubject = "invitation"
+
+// InvitationClaims identifican al usuario invitado y su versión: cualquier
+// cambio del usuario (incluido aceptar la invitación) la invalida
+type InvitationClaims struct {
+	UserID  uint  `json:"user_id"`
+	Version int64 `json:"version"`
+	jwt.RegisteredClaims
+}
+
+func GenerateInvitationToken(userID uint, version int64, ttl time.Duration) (string, error) {
+	claims := &InvitationClaims{
+		UserID:  userID,
+		Version: version,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   invitationSubject,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtKey)
+}
+
+func ValidateInvitationToken(tokenStr string) (*InvitationClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &InvitationClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return jwtKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*InvitationClaims); ok && token.Valid && claims.Subject == invitationSubject {
+		return claims, nil
+	}
+	return nil, errors.New("invitación inválida")
+}
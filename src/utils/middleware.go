@@ -1,15 +1,28 @@
 package utils
 
 import (
+	"api3/src/problem"
 	"net/http"
+	"os"
+	"strings"
 )
 
+// Cabeceras que el navegador puede enviar (Last-Event-ID para reanudar
+// /events) y leer de las respuestas
+const (
+	corsAllowHeaders  = "Content-Type, Authorization, If-Match, If-None-Match, Range, Last-Event-ID"
+	corsExposeHeaders = "ETag, Location, Content-Range, Accept-Ranges, X-Total-Count, Deprecation, Sunset, Link, Content-Disposition"
+)
+
+// CORS envuelve el router entero: responde los preflight y añade las
+// cabeceras a todas las respuestas
 func CORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Cabeceras CORS básicas
 		w.Header().Set("Access-Control-Allow-Origin", "*") // o tu dominio
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", corsAllowHeaders)
+		w.Header().Set("Access-Control-Expose-Headers", corsExposeHeaders)
 
 		// Si es OPTIONS, responde y termina
 		if r.Method == http.MethodOptions {
@@ -21,3 +34,20 @@ func CORS(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// RequireIfMatch rechaza con 428 las escrituras sin If-Match cuando
+// REQUIRE_IF_MATCH=true; si no, If-Match es opcional
+func RequireIfMatch(next http.HandlerFunc) http.HandlerFunc {
+	switch strings.ToLower(os.Getenv("REQUIRE_IF_MATCH")) {
+	case "1", "true", "yes":
+	default:
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Match") == "" {
+			problem.Write(w, r, problem.New(problem.CodePreconditionRequired).WithDetail("detail.send_etag"))
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,243 @@
+// Package webhooks envía los cambios de usuarios a los sistemas suscritos
+// (nóminas, impresión de credenciales): firma cada entrega, reintenta con
+// espera exponencial y deja en la lista de fallidas las que no llegan.
+package webhooks
+
+import (
+	"api3/src/events"
+	"api3/src/models"
+	"api3/src/repository"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Cabeceras de cada entrega
+const (
+	HeaderEvent     = "X-Zoo-Event"
+	HeaderDelivery  = "X-Zoo-Delivery"
+	HeaderSignature = "X-Zoo-Signature"
+)
+
+// Entregas que se reservan en cada pasada
+const batchSize = 50
+
+// Config ajusta los reintentos y el envío
+type Config struct {
+	MaxAttempts int           // intentos antes de pasar a la lista de fallidas
+	Backoff     time.Duration // espera tras el primer fallo; se duplica en cada uno
+	MaxBackoff  time.Duration // espera máxima entre intentos
+	Timeout     time.Duration // tiempo máximo de cada petición
+}
+
+// Dispatcher guarda una entrega por evento y webhook interesado y las envía
+// en segundo plano. Las entregas están en la BD: sobreviven a un reinicio y
+// con varias instancias cada una se envía una sola vez a la vez.
+type Dispatcher struct {
+	hooks  repository.WebhookRepository
+	cfg    Config
+	client *http.Client
+	wake   chan struct{}
+}
+
+func NewDispatcher(hooks repository.WebhookRepository, cfg Config) *Dispatcher {
+	client := &http.Client{
+		Timeout: cfg.Timeout,
+		// Una redirección cuenta como fallo: la URL registrada es la que se firma
+		CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	return &Dispatcher{hooks: hooks, cfg: cfg, client: client, wake: make(chan struct{}, 1)}
+}
+
+// Enqueue prepara las entregas del evento para los webhooks activos que lo
+// quieren y avisa al repartidor
+func (d *Dispatcher) Enqueue(ctx context.Context, e events.Event) error {
+	hooks, err := d.hooks.List(ctx)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	var deliveries []models.WebhookDelivery
+	for _, hook := range hooks {
+		if hook.Wants(e.Type) {
+			deliveries = append(deliveries, models.WebhookDelivery{
+				WebhookID:     hook.ID,
+				EventID:       e.ID,
+				EventType:     e.Type,
+				Payload:       string(payload),
+				Status:        models.DeliveryPending,
+				NextAttemptAt: &now,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := d.hooks.AddDeliveries(ctx, deliveries); err != nil {
+		return err
+	}
+	d.notify()
+	return nil
+}
+
+// Redeliver vuelve a poner en cola una entrega (entregada o fallida) con
+// todos sus intentos; el registro conserva los anteriores
+func (d *Dispatcher) Redeliver(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	delivery, err := d.hooks.GetDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	delivery.Status, delivery.Attempts, delivery.NextAttemptAt = models.DeliveryPending, 0, &now
+	if err := d.hooks.SaveDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	d.notify()
+	return delivery, nil
+}
+
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start envía las entregas pendientes cada interval, o antes si llegan
+// nuevas, hasta que se cancele ctx
+func (d *Dispatcher) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-d.wake:
+			}
+			for {
+				sent, err := d.DeliverDue(ctx)
+				if err != nil {
+					log.Println("⚠️  Webhooks:", err)
+				}
+				if err != nil || sent < batchSize {
+					break
+				}
+			}
+		}
+	}()
+}
+
+// DeliverDue envía las entregas cuyo turno llegó y devuelve cuántas intentó
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	// La reserva dura lo que puede tardar la tanda entera
+	lease := d.cfg.Timeout*batchSize + time.Minute
+	due, err := d.hooks.ClaimDue(ctx, time.Now().UTC(), lease, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i := range due {
+		if err := d.deliver(ctx, &due[i]); err != nil {
+			return i + 1, err
+		}
+	}
+	return len(due), nil
+}
+
+// Hace un intento y guarda el resultado: entregada, a la espera del
+// siguiente intento o fallida
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) error {
+	hook, err := d.hooks.Get(ctx, delivery.WebhookID)
+	if errors.Is(err, repository.ErrWebhookNotFound) {
+		return nil // se borró junto con sus entregas
+	}
+	if err != nil {
+		return err
+	}
+
+	started := time.Now()
+	status, sendErr := d.send(ctx, hook, delivery)
+	delivery.Attempts++
+	attempt := models.WebhookAttempt{
+		Attempt:    delivery.Attempts,
+		StatusCode: status,
+		DurationMs: time.Since(started).Milliseconds(),
+		CreatedAt:  started.UTC(),
+	}
+	delivery.LastStatus, delivery.LastError = status, ""
+	switch {
+	case sendErr == nil && status >= 200 && status < 300:
+		delivery.Status, delivery.NextAttemptAt = models.DeliveryDelivered, nil
+	default:
+		if sendErr != nil {
+			attempt.Error = sendErr.Error()
+		} else {
+			attempt.Error = fmt.Sprintf("respuesta %d", status)
+		}
+		delivery.LastError = attempt.Error
+		if delivery.Attempts >= d.cfg.MaxAttempts {
+			delivery.Status, delivery.NextAttemptAt = models.DeliveryDead, nil
+			log.Printf("⚠️  Webhook %d: la entrega %d pasó a fallidas tras %d intentos (%s)", hook.ID, delivery.ID, delivery.Attempts, attempt.Error)
+		} else {
+			next := time.Now().UTC().Add(d.backoff(delivery.Attempts))
+			delivery.NextAttemptAt = &next
+		}
+	}
+	err = d.hooks.RecordAttempt(ctx, delivery, attempt)
+	if errors.Is(err, repository.ErrDeliveryNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (d *Dispatcher) send(ctx context.Context, hook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "api-zoo-webhooks")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, time.Now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // para reutilizar la conexión
+	return resp.StatusCode, nil
+}
+
+// Espera antes del intento siguiente a attempts: Backoff·2^(attempts-1),
+// como mucho MaxBackoff, con hasta un 10 % al azar para que los reintentos
+// de muchas entregas no coincidan
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.cfg.Backoff
+	for i := 1; i < attempts && wait < d.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.cfg.MaxBackoff {
+		wait = d.cfg.MaxBackoff
+	}
+	if jitter := int64(wait / 10); jitter > 0 {
+		wait += time.Duration(rand.Int63n(jitter))
+	}
+	return wait
+}
@@ -0,0 +1,164 @@
+package webhooks
+
+import (
+	"api3/src/events"
+	"api3/src/models"
+	"api3/src/repository"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receiver es el sistema suscrito: comprueba la firma y responde con los
+// estados de statuses (el último se repite)
+type receiver struct {
+	t        *testing.T
+	secret   string
+	mu       sync.Mutex
+	statuses []int
+	calls    int
+	events   []string
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if err := Verify(rc.secret, r.Header.Get(HeaderSignature), body, time.Minute); err != nil {
+		rc.t.Errorf("firma: %v", err)
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	status := rc.statuses[min(rc.calls, len(rc.statuses)-1)]
+	rc.calls++
+	rc.events = append(rc.events, r.Header.Get(HeaderEvent))
+	w.WriteHeader(status)
+}
+
+func setup(t *testing.T, cfg Config, hooks ...*models.Webhook) (*Dispatcher, *repository.MemoryWebhookRepository) {
+	t.Helper()
+	repo := repository.NewMemoryWebhookRepository()
+	for _, hook := range hooks {
+		if err := repo.Create(context.Background(), hook); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewDispatcher(repo, cfg), repo
+}
+
+// Repite DeliverDue hasta que no quedan entregas pendientes
+func drain(t *testing.T, d *Dispatcher, repo *repository.MemoryWebhookRepository) {
+	t.Helper()
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		if _, err := d.DeliverDue(ctx); err != nil {
+			t.Fatal(err)
+		}
+		pending, _ := repo.Deliveries(ctx, repository.DeliveryFilter{Status: models.DeliveryPending})
+		if len(pending) == 0 {
+			return
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	t.Fatal("quedan entregas pendientes")
+}
+
+func publish(t *testing.T, d *Dispatcher, typ string) {
+	t.Helper()
+	e := events.Event{ID: 1, Type: typ, Time: time.Now(), User: &models.User{ID: 7, Username: "ana", Zona: "norte"}}
+	if err := d.Enqueue(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var fastRetries = Config{MaxAttempts: 3, Backoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond, Timeout: time.Second}
+
+func TestDeliverRetries(t *testing.T) {
+	rc := &receiver{t: t, secret: "s3cret", statuses: []int{http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusNoContent}}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	d, repo := setup(t, fastRetries,
+		&models.Webhook{URL: server.URL, Secret: rc.secret, Active: true},
+		&models.Webhook{URL: server.URL, Secret: rc.secret, Active: true, Events: models.EventList{events.UserDeleted}},
+		&models.Webhook{URL: server.URL, Secret: rc.secret, Active: false},
+	)
+	publish(t, d, events.UserCreated)
+	drain(t, d, repo)
+
+	// Solo el primer webhook quiere user.created
+	deliveries, _ := repo.Deliveries(context.Background(), repository.DeliveryFilter{})
+	if len(deliveries) != 1 {
+		t.Fatalf("%d entregas, se esperaba 1", len(deliveries))
+	}
+	delivery, _ := repo.GetDelivery(context.Background(), deliveries[0].ID)
+	if delivery.Status != models.DeliveryDelivered || delivery.Attempts != 3 || len(delivery.Log) != 3 {
+		t.Fatalf("entrega %s tras %d intentos (%d registrados)", delivery.Status, delivery.Attempts, len(delivery.Log))
+	}
+	if delivery.Log[0].StatusCode != http.StatusInternalServerError || delivery.Log[0].Error == "" {
+		t.Errorf("primer intento sin registrar: %+v", delivery.Log[0])
+	}
+	if rc.events[0] != events.UserCreated {
+		t.Errorf("cabecera %s = %q", HeaderEvent, rc.events[0])
+	}
+}
+
+func TestDeadLetterRedeliver(t *testing.T) {
+	rc := &receiver{t: t, secret: "s3cret", statuses: []int{http.StatusBadGateway}}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	d, repo := setup(t, fastRetries, &models.Webhook{URL: server.URL, Secret: rc.secret, Active: true})
+	publish(t, d, events.UserDeleted)
+	drain(t, d, repo)
+
+	dead, _ := repo.Deliveries(context.Background(), repository.DeliveryFilter{Status: models.DeliveryDead})
+	if len(dead) != 1 || dead[0].Attempts != fastRetries.MaxAttempts || dead[0].LastStatus != http.StatusBadGateway {
+		t.Fatalf("fallidas: %+v", dead)
+	}
+
+	// El receptor se arregla y se reenvía a mano
+	rc.mu.Lock()
+	rc.statuses = []int{http.StatusOK}
+	rc.mu.Unlock()
+	if _, err := d.Redeliver(context.Background(), dead[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	drain(t, d, repo)
+	delivery, _ := repo.GetDelivery(context.Background(), dead[0].ID)
+	if delivery.Status != models.DeliveryDelivered || len(delivery.Log) != fastRetries.MaxAttempts+1 {
+		t.Fatalf("tras reenviar: %s con %d intentos registrados", delivery.Status, len(delivery.Log))
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(nil, Config{Backoff: 10 * time.Second, MaxBackoff: time.Minute})
+	for _, tt := range []struct {
+		attempts int
+		want     time.Duration
+	}{{1, 10 * time.Second}, {2, 20 * time.Second}, {3, 40 * time.Second}, {4, time.Minute}, {30, time.Minute}} {
+		got := d.backoff(tt.attempts)
+		if got < tt.want || got >= tt.want+tt.want/10 {
+			t.Errorf("backoff(%d) = %v, se esperaba %v (+10 %%)", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"type":"user.created"}`)
+	header := Sign("s3cret", time.Now(), body)
+	if err := Verify("s3cret", header, body, time.Minute); err != nil {
+		t.Errorf("firma válida rechazada: %v", err)
+	}
+	if Verify("otro", header, body, time.Minute) == nil {
+		t.Error("se aceptó un secreto distinto")
+	}
+	if Verify("s3cret", header, []byte(`{}`), time.Minute) == nil {
+		t.Error("se aceptó un cuerpo distinto")
+	}
+	if Verify("s3cret", Sign("s3cret", time.Now().Add(-time.Hour), body), body, time.Minute) == nil {
+		t.Error("se aceptó una firma antigua")
+	}
+}